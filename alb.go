@@ -0,0 +1,108 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ServeALB serves a request sent by an Application Load Balancer to its Lambda
+// target group. The request is converted to the REST API shape used everywhere
+// else, so handlers and helpers work unchanged, and the response is converted back.
+// When the target group has multi-value headers enabled, the response uses them
+// too, as the load balancer expects. LambdaHandler calls it for ALB events.
+func (t *TreeMux) ServeALB(ctx context.Context, req events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	res, err := t.ServeLambda(ctx, fromALBRequest(req))
+	return toALBResponse(res, req.MultiValueHeaders != nil), err
+}
+
+// fromALBRequest converts an ALB request to a REST API request. The load balancer
+// passes the query parameters on as they were sent, so they are decoded here.
+func fromALBRequest(req events.ALBTargetGroupRequest) events.APIGatewayProxyRequest {
+	event := events.APIGatewayProxyRequest{
+		Resource:          req.Path,
+		Path:              req.Path,
+		HTTPMethod:        req.HTTPMethod,
+		Headers:           req.Headers,
+		MultiValueHeaders: req.MultiValueHeaders,
+		Body:              req.Body,
+		IsBase64Encoded:   req.IsBase64Encoded,
+	}
+	if len(req.QueryStringParameters) != 0 {
+		event.QueryStringParameters = make(map[string]string, len(req.QueryStringParameters))
+		for key, value := range req.QueryStringParameters {
+			event.QueryStringParameters[queryUnescape(key)] = queryUnescape(value)
+		}
+	}
+	if len(req.MultiValueQueryStringParameters) != 0 {
+		event.MultiValueQueryStringParameters = make(map[string][]string, len(req.MultiValueQueryStringParameters))
+		for key, values := range req.MultiValueQueryStringParameters {
+			decoded := make([]string, len(values))
+			for i, value := range values {
+				decoded[i] = queryUnescape(value)
+			}
+			event.MultiValueQueryStringParameters[queryUnescape(key)] = decoded
+		}
+		if event.QueryStringParameters == nil {
+			event.QueryStringParameters = make(map[string]string, len(event.MultiValueQueryStringParameters))
+			for key, values := range event.MultiValueQueryStringParameters {
+				if len(values) != 0 {
+					event.QueryStringParameters[key] = values[len(values)-1]
+				}
+			}
+		}
+	}
+	if event.Headers == nil && event.MultiValueHeaders != nil {
+		event.Headers = make(map[string]string, len(event.MultiValueHeaders))
+		for key, values := range event.MultiValueHeaders {
+			if len(values) != 0 {
+				event.Headers[key] = values[len(values)-1]
+			}
+		}
+	}
+	return event
+}
+
+// queryUnescape decodes a query parameter, keeping it as is when it is not
+// properly encoded.
+func queryUnescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// toALBResponse converts a REST API response to an ALB one, with only multi-value
+// headers when multiValue is set.
+func toALBResponse(res events.APIGatewayProxyResponse, multiValue bool) events.ALBTargetGroupResponse {
+	out := events.ALBTargetGroupResponse{
+		StatusCode:        res.StatusCode,
+		StatusDescription: strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode),
+		Body:              res.Body,
+		IsBase64Encoded:   res.IsBase64Encoded,
+	}
+	if !multiValue {
+		out.Headers = make(map[string]string, len(res.Headers)+len(res.MultiValueHeaders))
+		for key, value := range res.Headers {
+			out.Headers[key] = value
+		}
+		for key, values := range res.MultiValueHeaders {
+			if len(values) != 0 {
+				out.Headers[key] = values[len(values)-1]
+			}
+		}
+		return out
+	}
+
+	out.MultiValueHeaders = make(map[string][]string, len(res.Headers)+len(res.MultiValueHeaders))
+	for key, value := range res.Headers {
+		out.MultiValueHeaders[key] = []string{value}
+	}
+	for key, values := range res.MultiValueHeaders {
+		out.MultiValueHeaders[key] = append(out.MultiValueHeaders[key], values...)
+	}
+	return out
+}
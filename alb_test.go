@@ -0,0 +1,83 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func albRequest(method, path string) events.ALBTargetGroupRequest {
+	req := events.ALBTargetGroupRequest{HTTPMethod: method, Path: path}
+	req.RequestContext.ELB.TargetGroupArn = "arn:aws:elasticloadbalancing:eu-west-1:123:targetgroup/api/abc"
+	return req
+}
+
+func TestServeALB(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/user/:name", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			StatusCode:        200,
+			Headers:           map[string]string{"Content-Type": "text/plain"},
+			MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+			Body:              req.PathParameters["name"] + " " + req.QueryStringParameters["q"],
+		}, nil
+	})
+
+	req := albRequest("GET", "/user/bob")
+	req.QueryStringParameters = map[string]string{"q": "hello%20world"}
+	res, err := router.ServeALB(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || res.StatusDescription != "200 OK" {
+		t.Errorf("Expected 200 OK, saw %d %q", res.StatusCode, res.StatusDescription)
+	}
+	if res.Body != "bob hello world" {
+		t.Errorf("Unexpected body %q", res.Body)
+	}
+	if res.Headers["Content-Type"] != "text/plain" || res.Headers["Set-Cookie"] != "b=2" {
+		t.Errorf("Unexpected headers %v", res.Headers)
+	}
+	if res.MultiValueHeaders != nil {
+		t.Errorf("Expected no multi-value headers, saw %v", res.MultiValueHeaders)
+	}
+
+	req = albRequest("GET", "/user/alice")
+	req.MultiValueHeaders = map[string][]string{"accept": {"text/plain"}}
+	req.MultiValueQueryStringParameters = map[string][]string{"q": {"a%2Bb"}}
+	res, _ = router.ServeALB(context.Background(), req)
+	if res.Body != "alice a+b" {
+		t.Errorf("Unexpected body %q", res.Body)
+	}
+	if res.Headers != nil {
+		t.Errorf("Expected only multi-value headers, saw %v", res.Headers)
+	}
+	if cookies := res.MultiValueHeaders["Set-Cookie"]; len(cookies) != 2 {
+		t.Errorf("Expected both cookies, saw %v", cookies)
+	}
+
+	res, _ = router.ServeALB(context.Background(), albRequest("GET", "/missing"))
+	if res.StatusCode != 404 || res.StatusDescription != "404 Not Found" {
+		t.Errorf("Expected 404 Not Found, saw %d %q", res.StatusCode, res.StatusDescription)
+	}
+}
+
+func TestLambdaHandlerALB(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/ping", simpleHandler)
+
+	raw, _ := json.Marshal(albRequest("GET", "/ping"))
+	out, err := router.LambdaHandler()(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := out.(events.ALBTargetGroupResponse)
+	if !ok {
+		t.Fatalf("Expected an ALB response, saw %T", out)
+	}
+	if res.StatusCode != 204 {
+		t.Errorf("Expected 204, saw %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,225 @@
+package lambdarouter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// AuthorizerFailureMode decides what happens to a request served locally when the
+// authorizer returns an error.
+type AuthorizerFailureMode int
+
+const (
+	// FailClosed rejects the request with 403 Forbidden. This is the default.
+	FailClosed AuthorizerFailureMode = iota
+	// FailOpen lets the request through to its handler, without authorizer context.
+	FailOpen
+)
+
+// SetAuthorizerFailureMode sets what ServeHTTP does when the authorizer returns an
+// error, such as when the identity provider can not be reached.
+func (t *TreeMux) SetAuthorizerFailureMode(mode AuthorizerFailureMode) {
+	t.authorizerFailureMode = mode
+}
+
+// SetAuthorizerCacheTTL caches the responses of the authorizer for d, like API
+// Gateway does, so that the requests served locally with the same identity source
+// reuse the context of the first one instead of calling the authorizer again. The
+// identity source is the Authorization header, unless SetAuthorizerIdentitySource
+// says otherwise. Requests without it and the errors of the authorizer are not
+// cached. A TTL of 0 disables the cache.
+func (t *TreeMux) SetAuthorizerCacheTTL(d time.Duration) {
+	if d <= 0 {
+		t.authorizerCache = nil
+		return
+	}
+	t.authorizerCache = &authorizerCache{ttl: d, now: time.Now, entries: make(map[string]authorizerCacheEntry)}
+}
+
+// SetAuthorizerIdentitySource sets the request headers whose values key the
+// authorizer cache, as the identity source of the authorizer does on API Gateway.
+func (t *TreeMux) SetAuthorizerIdentitySource(headers ...string) {
+	t.authorizerIdentitySource = headers
+}
+
+// authorize calls the authorizer for event, going through the cache when it is
+// enabled.
+func (t *TreeMux) authorize(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	cache := t.authorizerCache
+	if cache == nil {
+		return t.authorizer(ctx, GenerateLambdaAuthorizer(event))
+	}
+	key, ok := t.identity(event)
+	if !ok {
+		return t.authorizer(ctx, GenerateLambdaAuthorizer(event))
+	}
+	if res, ok := cache.get(key); ok {
+		return res, nil
+	}
+	res, err := t.authorizer(ctx, GenerateLambdaAuthorizer(event))
+	if err == nil {
+		cache.set(key, res)
+	}
+	return res, err
+}
+
+// identity returns the values of the identity source of event, or false when one
+// of them is missing.
+func (t *TreeMux) identity(event events.APIGatewayProxyRequest) (string, bool) {
+	headers := t.authorizerIdentitySource
+	if len(headers) == 0 {
+		headers = []string{"Authorization"}
+	}
+	values := make([]string, len(headers))
+	for i, header := range headers {
+		if values[i] = Header(event, header); values[i] == "" {
+			return "", false
+		}
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// authorizerCache holds the responses of the authorizer by identity.
+type authorizerCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]authorizerCacheEntry
+}
+
+type authorizerCacheEntry struct {
+	res     events.APIGatewayCustomAuthorizerResponse
+	expires time.Time
+}
+
+func (c *authorizerCache) get(key string) (events.APIGatewayCustomAuthorizerResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return events.APIGatewayCustomAuthorizerResponse{}, false
+	}
+	return entry.res, true
+}
+
+func (c *authorizerCache) set(key string, res events.APIGatewayCustomAuthorizerResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = authorizerCacheEntry{res: res, expires: now.Add(c.ttl)}
+}
+
+// policyAllows evaluates policy for invoking the method of arn the way API Gateway
+// does: an explicit Deny wins over any Allow, and without a matching Allow the
+// request is denied.
+func policyAllows(policy events.APIGatewayCustomAuthorizerPolicy, arn string) bool {
+	allowed := false
+	for _, statement := range policy.Statement {
+		if !matchesAny(statement.Action, "execute-api:Invoke", true) || !matchesAny(statement.Resource, arn, false) {
+			continue
+		}
+		switch {
+		case strings.EqualFold(statement.Effect, "Deny"):
+			return false
+		case strings.EqualFold(statement.Effect, "Allow"):
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// matchesAny reports whether value matches one of patterns, in which * stands for
+// any sequence of characters and ? for any single one, as in IAM policies.
+func matchesAny(patterns []string, value string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		expr := regexp.QuoteMeta(pattern)
+		expr = strings.Replace(expr, `\*`, ".*", -1)
+		expr = strings.Replace(expr, `\?`, ".", -1)
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		if matched, _ := regexp.MatchString("^"+expr+"$", value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// API Gateway stringifies every value of the authorizer context before it reaches
+// the handler, while the local server passes them through untouched. The accessors
+// below accept both forms.
+
+func authContextValue(req events.APIGatewayProxyRequest, key string) (interface{}, bool) {
+	v, ok := req.RequestContext.Authorizer[key]
+	if !ok || v == nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// AuthContextString returns the authorizer context value for key as a string.
+func AuthContextString(req events.APIGatewayProxyRequest, key string) (string, bool) {
+	v, ok := authContextValue(req, key)
+	if !ok {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	return fmt.Sprint(v), true
+}
+
+// AuthContextInt returns the authorizer context value for key as an int. The
+// boolean is false when the key is missing or the value is not an integer.
+func AuthContextInt(req events.APIGatewayProxyRequest, key string) (int, bool) {
+	v, ok := authContextValue(req, key)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n != float64(int(n)) {
+			return 0, false
+		}
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// AuthContextBool returns the authorizer context value for key as a bool. The
+// boolean is false when the key is missing or the value is not a boolean.
+func AuthContextBool(req events.APIGatewayProxyRequest, key string) (bool, bool) {
+	v, ok := authContextValue(req, key)
+	if !ok {
+		return false, false
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		parsed, err := strconv.ParseBool(b)
+		return parsed, err == nil
+	}
+	return false, false
+}
@@ -0,0 +1,196 @@
+package lambdarouter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAuthContextAccessors(t *testing.T) {
+	req := events.APIGatewayProxyRequest{}
+	req.RequestContext.Authorizer = map[string]interface{}{
+		"user":   "bob",
+		"tenant": "42",
+		"level":  float64(3),
+		"admin":  "true",
+	}
+
+	if user, ok := AuthContextString(req, "user"); !ok || user != "bob" {
+		t.Errorf("Expected user bob, saw %q (%v)", user, ok)
+	}
+
+	if tenant, ok := AuthContextInt(req, "tenant"); !ok || tenant != 42 {
+		t.Errorf("Expected tenant 42, saw %d (%v)", tenant, ok)
+	}
+
+	if level, ok := AuthContextInt(req, "level"); !ok || level != 3 {
+		t.Errorf("Expected level 3, saw %d (%v)", level, ok)
+	}
+
+	if admin, ok := AuthContextBool(req, "admin"); !ok || !admin {
+		t.Errorf("Expected admin true, saw %v (%v)", admin, ok)
+	}
+
+	if _, ok := AuthContextInt(req, "user"); ok {
+		t.Error("Expected non-numeric user to fail AuthContextInt")
+	}
+
+	if v, ok := AuthContextString(req, "missing"); ok || v != "" {
+		t.Errorf("Expected missing key to return empty and false, saw %q (%v)", v, ok)
+	}
+
+	if _, ok := AuthContextInt(events.APIGatewayProxyRequest{}, "tenant"); ok {
+		t.Error("Expected lookup without authorizer context to fail")
+	}
+}
+
+func TestAuthorizerFailureMode(t *testing.T) {
+	router := New()
+	router.GET("/user", simpleHandler)
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		return events.APIGatewayCustomAuthorizerResponse{}, errors.New("identity provider unreachable")
+	})
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/__stage__/user", nil)
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(); code != http.StatusForbidden {
+		t.Errorf("Expected the request to be rejected by default, saw %d", code)
+	}
+
+	router.SetAuthorizerFailureMode(FailOpen)
+	if code := serve(); code != http.StatusNoContent {
+		t.Errorf("Expected the request to reach the handler when failing open, saw %d", code)
+	}
+
+	router.SetAuthorizerFailureMode(FailClosed)
+	if code := serve(); code != http.StatusForbidden {
+		t.Errorf("Expected the request to be rejected when failing closed, saw %d", code)
+	}
+}
+
+func TestAuthorizerCache(t *testing.T) {
+	var calls int
+	router := New()
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		calls++
+		return events.APIGatewayCustomAuthorizerResponse{
+			PolicyDocument: policy("Allow", "*"),
+			Context:        map[string]interface{}{"user": req.Headers["Authorization"]},
+		}, nil
+	})
+	router.SetAuthorizerCacheTTL(time.Minute)
+	now := time.Now()
+	router.authorizerCache.now = func() time.Time { return now }
+
+	var user string
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		user, _ = AuthContextString(req, "user")
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	serve := func(token string) {
+		r, _ := http.NewRequest("GET", "/__stage__/abc", nil)
+		if token != "" {
+			r.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve("alice")
+	serve("alice")
+	if calls != 1 || user != "alice" {
+		t.Errorf("Expected one call for a cached token, saw %d and user %q", calls, user)
+	}
+
+	serve("bob")
+	if calls != 2 || user != "bob" {
+		t.Errorf("Expected another token to call the authorizer, saw %d and user %q", calls, user)
+	}
+
+	serve("")
+	serve("")
+	if calls != 4 {
+		t.Errorf("Expected requests without a token not to be cached, saw %d calls", calls)
+	}
+
+	now = now.Add(time.Minute)
+	serve("alice")
+	if calls != 5 {
+		t.Errorf("Expected an expired entry to call the authorizer again, saw %d calls", calls)
+	}
+	serve("alice")
+	if calls != 5 {
+		t.Errorf("Expected the new response to be cached, saw %d calls", calls)
+	}
+
+	router.SetAuthorizerCacheTTL(0)
+	serve("alice")
+	if calls != 6 {
+		t.Errorf("Expected a TTL of 0 to disable the cache, saw %d calls", calls)
+	}
+}
+
+func policy(effect string, resources ...string) events.APIGatewayCustomAuthorizerPolicy {
+	return events.APIGatewayCustomAuthorizerPolicy{
+		Version: "2012-10-17",
+		Statement: []events.IAMPolicyStatement{{
+			Action:   []string{"execute-api:Invoke"},
+			Effect:   effect,
+			Resource: resources,
+		}},
+	}
+}
+
+func TestAuthorizerPolicy(t *testing.T) {
+	var document events.APIGatewayCustomAuthorizerPolicy
+	var arn string
+	router := New()
+	router.GET("/users/:id", simpleHandler)
+	router.DELETE("/users/:id", simpleHandler)
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		arn = req.MethodArn
+		return events.APIGatewayCustomAuthorizerResponse{PolicyDocument: document}, nil
+	})
+	serve := func(method string) int {
+		r, _ := http.NewRequest(method, "/prod/users/5", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	document = policy("Allow", "arn:aws:execute-api:*:*:*/prod/GET/users/*")
+	if code := serve("GET"); code != http.StatusNoContent {
+		t.Errorf("Expected an allowed request to reach the handler, saw %d", code)
+	}
+	if !strings.HasSuffix(arn, ":localhost/prod/GET/users/5") {
+		t.Errorf("Expected the method ARN of GET /users/5 on prod, saw %s", arn)
+	}
+
+	if code := serve("DELETE"); code != http.StatusForbidden {
+		t.Errorf("Expected a request matching no Allow to be forbidden, saw %d", code)
+	}
+
+	document = policy("Allow", "*")
+	document.Statement = append(document.Statement, policy("Deny", "arn:aws:execute-api:*:*:*/*/DELETE/*").Statement...)
+	if code := serve("GET"); code != http.StatusNoContent {
+		t.Errorf("Expected GET to be allowed next to a Deny for DELETE, saw %d", code)
+	}
+	if code := serve("DELETE"); code != http.StatusForbidden {
+		t.Errorf("Expected an explicit Deny to win over Allow, saw %d", code)
+	}
+
+	document = events.APIGatewayCustomAuthorizerPolicy{}
+	if code := serve("GET"); code != http.StatusForbidden {
+		t.Errorf("Expected an empty policy to deny, saw %d", code)
+	}
+}
@@ -0,0 +1,56 @@
+package lambdarouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BadRequestHandler answers a request ServeHTTP refused to route because it is
+// malformed. err tells what is wrong with it.
+type BadRequestHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// SimpleBadRequestHandler answers with 400 Bad Request and a JSON body reporting
+// err. ServeHTTP uses it when TreeMux.BadRequestHandler is not set.
+func SimpleBadRequestHandler(w http.ResponseWriter, r *http.Request, err error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
+
+// validateRequest checks that r can be converted to an event and routed: it needs
+// a method and an origin-form URL whose path starts with a slash.
+func validateRequest(r *http.Request) error {
+	if !validMethod(r.Method) {
+		return fmt.Errorf("lambdarouter: invalid method %q", r.Method)
+	}
+	if r.URL == nil {
+		return errors.New("lambdarouter: missing URL")
+	}
+	if r.RequestURI != "" {
+		if _, err := url.ParseRequestURI(r.RequestURI); err != nil {
+			return fmt.Errorf("lambdarouter: invalid URL %q", r.RequestURI)
+		}
+	}
+	if !strings.HasPrefix(r.URL.Path, "/") {
+		return fmt.Errorf("lambdarouter: invalid path %q", r.URL.Path)
+	}
+	return nil
+}
+
+// validMethod reports whether method is a non-empty HTTP token.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for _, c := range method {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
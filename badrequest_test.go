@@ -0,0 +1,56 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestBadRequest(t *testing.T) {
+	var called bool
+	router := New()
+	router.GET("/*path", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	emptyMethod := httptest.NewRequest("GET", "/__stage__/abc", nil)
+	emptyMethod.Method = ""
+	badMethod := httptest.NewRequest("GET", "/__stage__/abc", nil)
+	badMethod.Method = "GET /abc"
+	badURL := httptest.NewRequest("GET", "/__stage__/abc", nil)
+	badURL.RequestURI = "/__stage__/%zz"
+	relative := httptest.NewRequest("GET", "/__stage__/abc", nil)
+	relative.URL.Path = "abc"
+
+	for _, r := range []*http.Request{emptyMethod, badMethod, badURL, relative} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		var body map[string]string
+		if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &body) != nil || body["error"] == "" {
+			t.Errorf("Expected 400 with an error for %q %q, saw %d %s", r.Method, r.RequestURI, w.Code, w.Body.String())
+		}
+	}
+	if called {
+		t.Error("Expected malformed requests not to be routed")
+	}
+
+	router.BadRequestHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, emptyMethod)
+	if w.Code != http.StatusTeapot {
+		t.Errorf("Expected the custom handler to answer, saw %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/__stage__/abc", nil))
+	if w.Code != 200 || !called {
+		t.Errorf("Expected a valid request to be routed, saw %d", w.Code)
+	}
+}
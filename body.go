@@ -0,0 +1,289 @@
+package lambdarouter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrNoRequestBody is returned by BodyJSON when the context was not created by
+// the router for a request.
+var ErrNoRequestBody = errors.New("lambdarouter: no request body in context")
+
+// ErrEmptyBody is returned by DecodeStream and BindJSON when the request has no body.
+var ErrEmptyBody = errors.New("lambdarouter: empty request body")
+
+type jsonBody struct {
+	req  events.APIGatewayProxyRequest
+	once sync.Once
+	v    map[string]interface{}
+	err  error
+}
+
+// RequestBody returns the body of req, decoded from base64 when API Gateway, or
+// the local server, encoded it because of its binary content type.
+func RequestBody(req events.APIGatewayProxyRequest) ([]byte, error) {
+	if req.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(req.Body)
+	}
+	return []byte(req.Body), nil
+}
+
+// BinaryMediaTypes lists the content types whose request bodies RequestToLambda and
+// the local server base64-encode, like the binary media types of an API Gateway
+// REST API. Entries may end with a wildcard, as in "image/*", and "*/*" matches
+// everything. When empty, every body that is not text, JSON, XML or a form is
+// encoded.
+var BinaryMediaTypes []string
+
+// isBinaryContentType reports whether a body of contentType is encoded according
+// to BinaryMediaTypes.
+func isBinaryContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if len(BinaryMediaTypes) != 0 {
+		for _, binary := range BinaryMediaTypes {
+			binary = strings.ToLower(binary)
+			if binary == "*/*" || binary == mediaType ||
+				strings.HasSuffix(binary, "/*") && strings.HasPrefix(mediaType, binary[:len(binary)-1]) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return false
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-www-form-urlencoded":
+		return false
+	}
+	return true
+}
+
+// encodeBody base64-encodes the body of e when its content type is binary, which
+// is what API Gateway does before invoking the function.
+func encodeBody(e *events.APIGatewayProxyRequest) {
+	if e.Body == "" || e.IsBase64Encoded || !isBinaryContentType(Header(*e, "Content-Type")) {
+		return
+	}
+	e.Body = encodeBase64([]byte(e.Body))
+	e.IsBase64Encoded = true
+}
+
+// TransformBody returns a middleware replacing the body of requests with the one
+// returned by transform, before the handler sees it through req.Body, RequestBody
+// or BodyJSON, for example to unwrap an envelope or decrypt the payload:
+//
+//	router.NewGroup("/hooks").Use(lambdarouter.TransformBody(unwrap))
+//
+// transform receives the decoded body. The new body is base64-encoded unless it is
+// valid UTF-8. An error of transform is answered with 400 Bad Request without
+// calling the handler. Bodies streamed with Route.StreamBody are left alone.
+func TransformBody(transform func(ctx context.Context, body []byte) ([]byte, error)) func(HandlerFunc) HandlerFunc {
+	return func(h HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			if _, streamed := ctx.Value(bodyReaderContextKey).(io.Reader); streamed {
+				return h(ctx, req)
+			}
+			body, err := RequestBody(req)
+			if err == nil {
+				body, err = transform(ctx, body)
+			}
+			if err != nil {
+				return LambdaBadRequest(ctx, req, err)
+			}
+
+			if utf8.Valid(body) {
+				req.Body, req.IsBase64Encoded = string(body), false
+			} else {
+				req.Body, req.IsBase64Encoded = encodeBase64(body), true
+			}
+			ctx = context.WithValue(ctx, jsonBodyContextKey, &jsonBody{req: req})
+			return h(ctx, req)
+		}
+	}
+}
+
+// BodyJSON returns the JSON object sent as the body of the request being served
+// with ctx. The body is decoded on the first call only, so later calls from the
+// same handler or its middleware return the same map and error.
+func BodyJSON(ctx context.Context) (map[string]interface{}, error) {
+	body, ok := ctx.Value(jsonBodyContextKey).(*jsonBody)
+	if !ok {
+		return nil, ErrNoRequestBody
+	}
+
+	body.once.Do(func() {
+		var data []byte
+		if data, body.err = RequestBody(body.req); body.err == nil {
+			body.err = json.Unmarshal(data, &body.v)
+		}
+	})
+	return body.v, body.err
+}
+
+// UnknownFieldError is returned by BindStrict when the body has a field the target
+// struct has no place for.
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+// Bind decodes the JSON body of req into v. Fields of the body v has no place for
+// are ignored.
+func Bind(req events.APIGatewayProxyRequest, v interface{}) error {
+	return bind(req, v, false)
+}
+
+// BindStrict decodes the JSON body of req into v like Bind, but returns an
+// UnknownFieldError for the first field of the body v has no place for, which
+// catches typos of clients early.
+func BindStrict(req events.APIGatewayProxyRequest, v interface{}) error {
+	return bind(req, v, true)
+}
+
+// MalformedBodyError is returned by BindJSON when the body is not valid JSON, or
+// does not fit the target. Its message is meant for clients, as with
+// LambdaBadRequest.
+type MalformedBodyError struct {
+	Err error
+}
+
+func (e *MalformedBodyError) Error() string {
+	switch err := e.Err.(type) {
+	case *json.SyntaxError:
+		return fmt.Sprintf("malformed JSON body: %s at offset %d", err.Error(), err.Offset)
+	case *json.UnmarshalTypeError:
+		if err.Field != "" {
+			return fmt.Sprintf("malformed JSON body: field %q can not be a %s", err.Field, err.Value)
+		}
+		return fmt.Sprintf("malformed JSON body: can not be a %s", err.Value)
+	case base64.CorruptInputError:
+		return "malformed base64 body"
+	}
+	return "malformed JSON body: " + e.Err.Error()
+}
+
+func (e *MalformedBodyError) Unwrap() error {
+	return e.Err
+}
+
+// BindJSON decodes the JSON body of req into v, decoding it from base64 first when
+// it is encoded. Unlike Bind, it returns ErrEmptyBody when there is no body and a
+// MalformedBodyError describing the problem when it can not be decoded, which can
+// be answered as is:
+//
+//	var order Order
+//	if err := lambdarouter.BindJSON(req, &order); err != nil {
+//		return lambdarouter.LambdaBadRequest(ctx, req, err)
+//	}
+func BindJSON(req events.APIGatewayProxyRequest, v interface{}) error {
+	err := bind(req, v, false)
+	if err == io.EOF {
+		// The decoder found nothing but white space.
+		return ErrEmptyBody
+	} else if err != nil {
+		return &MalformedBodyError{Err: err}
+	}
+	return nil
+}
+
+func bind(req events.APIGatewayProxyRequest, v interface{}, strict bool) error {
+	data, err := RequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	err = decoder.Decode(v)
+	// The json package has no error type for unknown fields.
+	const unknownField = "json: unknown field "
+	if err != nil && strings.HasPrefix(err.Error(), unknownField) {
+		return &UnknownFieldError{Field: strings.Trim(err.Error()[len(unknownField):], `"`)}
+	}
+	return err
+}
+
+// BodyReader returns the body of the request being served with ctx as a stream.
+// For routes registered with Route.StreamBody and served locally, it reads from the
+// connection as the handler consumes it, otherwise it reads the decoded req.Body.
+func BodyReader(ctx context.Context, req events.APIGatewayProxyRequest) io.Reader {
+	if body, ok := ctx.Value(bodyReaderContextKey).(io.Reader); ok {
+		return body
+	}
+	var body io.Reader = strings.NewReader(req.Body)
+	if req.IsBase64Encoded {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return body
+}
+
+// MultipartReader returns a reader over the parts of a multipart request body, such
+// as a file upload, reading from BodyReader. Together with Route.StreamBody, this
+// lets handlers copy large files to storage without buffering them. It returns
+// http.ErrNotMultipart when the request is not multipart.
+func MultipartReader(ctx context.Context, req events.APIGatewayProxyRequest) (*multipart.Reader, error) {
+	contentType, _ := headerValue(req.Headers, "Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, http.ErrNotMultipart
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, http.ErrMissingBoundary
+	}
+	return multipart.NewReader(BodyReader(ctx, req), boundary), nil
+}
+
+// DecodeStream returns a JSON decoder over the body of the request being served
+// with ctx, which lets handlers of bulk endpoints process large arrays one element
+// at a time with Token and Decode instead of unmarshaling all of them at once:
+//
+//	dec, err := lambdarouter.DecodeStream(ctx, req)
+//	...
+//	dec.Token() // [
+//	for dec.More() {
+//		var item Item
+//		if err := dec.Decode(&item); err != nil {
+//			...
+//		}
+//	}
+//
+// The body is read through BodyReader, so it is streamed from the connection for
+// routes registered with Route.StreamBody, and decoded from base64 on the fly
+// otherwise. It returns ErrEmptyBody when there is no body.
+func DecodeStream(ctx context.Context, req events.APIGatewayProxyRequest) (*json.Decoder, error) {
+	body := bufio.NewReader(BodyReader(ctx, req))
+	if _, err := body.Peek(1); err == io.EOF {
+		return nil, ErrEmptyBody
+	} else if err != nil {
+		return nil, err
+	}
+	return json.NewDecoder(body), nil
+}
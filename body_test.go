@@ -0,0 +1,313 @@
+package lambdarouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type bindUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestBind(t *testing.T) {
+	req := events.APIGatewayProxyRequest{Body: `{"name": "bob", "age": 42, "nmae": "typo"}`}
+
+	var lenient bindUser
+	if err := Bind(req, &lenient); err != nil {
+		t.Errorf("Lenient binding expected no error, saw %v", err)
+	}
+	if lenient.Name != "bob" || lenient.Age != 42 {
+		t.Errorf("Expected bob aged 42, saw %+v", lenient)
+	}
+
+	var strict bindUser
+	err := BindStrict(req, &strict)
+	fieldErr, ok := err.(*UnknownFieldError)
+	if !ok || fieldErr.Field != "nmae" {
+		t.Fatalf("Strict binding expected an unknown field error for nmae, saw %v", err)
+	}
+	res, _ := LambdaBadRequest(context.Background(), req, err)
+	if res.StatusCode != http.StatusBadRequest || res.Body != `{"error":"unknown field \"nmae\""}` {
+		t.Errorf("Expected a 400 naming the field, saw %d %s", res.StatusCode, res.Body)
+	}
+
+	req = events.APIGatewayProxyRequest{
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"name": "alice"}`)),
+		IsBase64Encoded: true,
+	}
+	if err := BindStrict(req, &strict); err != nil || strict.Name != "alice" {
+		t.Errorf("Expected alice from a base64 body, saw %+v, %v", strict, err)
+	}
+
+	if err := Bind(events.APIGatewayProxyRequest{Body: `{"name": 1}`}, &lenient); err == nil {
+		t.Error("Expected an error for a mistyped field")
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	var user bindUser
+	if err := BindJSON(events.APIGatewayProxyRequest{Body: `{"name": "bob", "age": 42}`}, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "bob" || user.Age != 42 {
+		t.Errorf("Expected bob aged 42, saw %+v", user)
+	}
+
+	req := events.APIGatewayProxyRequest{
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"name": "alice"}`)),
+		IsBase64Encoded: true,
+	}
+	if err := BindJSON(req, &user); err != nil || user.Name != "alice" {
+		t.Errorf("Expected alice from a base64 body, saw %+v, %v", user, err)
+	}
+
+	tests := []struct {
+		req      events.APIGatewayProxyRequest
+		expected string
+	}{
+		{
+			events.APIGatewayProxyRequest{Body: `{"name": "bob",}`},
+			"malformed JSON body: invalid character '}' looking for beginning of object key string at offset 16",
+		},
+		{
+			events.APIGatewayProxyRequest{Body: `{"name": 1}`},
+			`malformed JSON body: field "name" can not be a number`,
+		},
+		{
+			events.APIGatewayProxyRequest{Body: `{"name"`, IsBase64Encoded: true},
+			"malformed base64 body",
+		},
+	}
+	for _, tc := range tests {
+		err := BindJSON(tc.req, &user)
+		if _, ok := err.(*MalformedBodyError); !ok || err.Error() != tc.expected {
+			t.Errorf("Expected %q for %s, saw %v", tc.expected, tc.req.Body, err)
+		}
+	}
+
+	if err := BindJSON(events.APIGatewayProxyRequest{Body: " "}, &user); err != ErrEmptyBody {
+		t.Errorf("Expected ErrEmptyBody, saw %v", err)
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestMultipartReaderStreaming(t *testing.T) {
+	file := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("upload", "data.bin")
+	part.Write(file)
+	mw.Close()
+	body := &countingReader{r: bytes.NewReader(form.Bytes())}
+
+	var stored bytes.Buffer
+	var readBeforeCopy int
+	router := New()
+	router.POST("/upload", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		mr, err := MultipartReader(ctx, req)
+		if err != nil {
+			return LambdaBadRequest(ctx, req, err)
+		}
+		p, err := mr.NextPart()
+		if err != nil {
+			return LambdaBadRequest(ctx, req, err)
+		}
+		readBeforeCopy = body.read
+		io.Copy(&stored, p)
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}).StreamBody()
+
+	r, _ := http.NewRequest("POST", "/__stage__/upload", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, saw %d: %s", w.Code, w.Body.String())
+	}
+	if !bytes.Equal(stored.Bytes(), file) {
+		t.Errorf("Expected the %d bytes of the file, saw %d bytes", len(file), stored.Len())
+	}
+	if readBeforeCopy >= len(file) {
+		t.Errorf("Expected the body to be streamed, but %d bytes were read before the part", readBeforeCopy)
+	}
+}
+
+func TestMultipartReaderLambda(t *testing.T) {
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("name", "bob")
+	mw.Close()
+	req := events.APIGatewayProxyRequest{
+		Headers:         map[string]string{"content-type": mw.FormDataContentType()},
+		Body:            base64.StdEncoding.EncodeToString(form.Bytes()),
+		IsBase64Encoded: true,
+	}
+
+	mr, err := MultipartReader(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := io.ReadAll(p); p.FormName() != "name" || string(value) != "bob" {
+		t.Errorf("Expected the field name=bob, saw %s=%s", p.FormName(), value)
+	}
+
+	if _, err := MultipartReader(context.Background(), events.APIGatewayProxyRequest{}); err != http.ErrNotMultipart {
+		t.Errorf("Expected ErrNotMultipart without a multipart body, saw %v", err)
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+	body := `[{"id": 1}, {"id": 2}, {"id": 3}]`
+
+	for _, req := range []events.APIGatewayProxyRequest{
+		{Body: body},
+		{Body: base64.StdEncoding.EncodeToString([]byte(body)), IsBase64Encoded: true},
+	} {
+		dec, err := DecodeStream(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			t.Fatalf("Expected the array to start, saw %v, %v", tok, err)
+		}
+		var ids []int
+		for dec.More() {
+			var it item
+			if err := dec.Decode(&it); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, it.ID)
+		}
+		if !reflect.DeepEqual(ids, []int{1, 2, 3}) {
+			t.Errorf("Expected the elements 1, 2 and 3 one by one, saw %v", ids)
+		}
+	}
+
+	if _, err := DecodeStream(context.Background(), events.APIGatewayProxyRequest{}); err != ErrEmptyBody {
+		t.Errorf("Expected ErrEmptyBody without a body, saw %v", err)
+	}
+}
+
+func TestRequestBodyBinary(t *testing.T) {
+	r := httptest.NewRequest("POST", "/upload", bytes.NewReader(pngHeader))
+	r.Header.Set("Content-Type", "image/png")
+	req, _ := RequestToLambda(r)
+	if !req.IsBase64Encoded || req.Body != base64.StdEncoding.EncodeToString(pngHeader) {
+		t.Errorf("Expected the PNG body to be base64-encoded, saw %v %q", req.IsBase64Encoded, req.Body)
+	}
+	if body, err := RequestBody(req); err != nil || !bytes.Equal(body, pngHeader) {
+		t.Errorf("Expected RequestBody to return the PNG bytes, saw %v %v", body, err)
+	}
+
+	r = httptest.NewRequest("POST", "/upload", bytes.NewBufferString(`{"name":"bob"}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req, _ = RequestToLambda(r)
+	if req.IsBase64Encoded || req.Body != `{"name":"bob"}` {
+		t.Errorf("Expected a JSON body to be left as is, saw %v %q", req.IsBase64Encoded, req.Body)
+	}
+
+	BinaryMediaTypes = []string{"application/*"}
+	defer func() { BinaryMediaTypes = nil }()
+	r = httptest.NewRequest("POST", "/upload", bytes.NewBufferString(`{"name":"bob"}`))
+	r.Header.Set("Content-Type", "application/json")
+	req, _ = RequestToLambda(r)
+	if !req.IsBase64Encoded {
+		t.Error("Expected application/* in BinaryMediaTypes to encode a JSON body")
+	}
+	r = httptest.NewRequest("POST", "/upload", bytes.NewReader(pngHeader))
+	r.Header.Set("Content-Type", "image/png")
+	if req, _ = RequestToLambda(r); req.IsBase64Encoded {
+		t.Error("Expected image/png not to be encoded when missing from BinaryMediaTypes")
+	}
+
+	if _, err := RequestBody(events.APIGatewayProxyRequest{Body: "not base64!", IsBase64Encoded: true}); err == nil {
+		t.Error("Expected an error for an invalid base64 body")
+	}
+}
+
+func TestRequestBodyBinaryServeHTTP(t *testing.T) {
+	router := New()
+	router.MaxRequestBytes = int64(len(pngHeader))
+	router.POST("/upload", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		body, err := RequestBody(req)
+		if err != nil {
+			return LambdaBadRequest(ctx, req, err)
+		}
+		return Binary(body, Header(req, "Content-Type"), http.StatusOK), nil
+	})
+
+	r := httptest.NewRequest("POST", "/__stage__/upload", bytes.NewReader(pngHeader))
+	r.Header.Set("Content-Type", "image/png")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), pngHeader) {
+		t.Errorf("Expected the PNG body to round-trip, saw %d %v", w.Code, w.Body.Bytes())
+	}
+}
+
+func TestTransformBody(t *testing.T) {
+	unwrap := func(ctx context.Context, body []byte) ([]byte, error) {
+		var envelope struct{ Payload string }
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(envelope.Payload)
+	}
+
+	var body string
+	var name interface{}
+	router := newLambdaRouter()
+	router.Use(TransformBody(unwrap))
+	router.POST("/hooks", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		data, _ := RequestBody(req)
+		body = string(data)
+		if object, err := BodyJSON(ctx); err == nil {
+			name = object["name"]
+		}
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	payload := base64.StdEncoding.EncodeToString([]byte(`{"name":"bob"}`))
+	req := events.APIGatewayProxyRequest{HTTPMethod: "POST", Resource: "/hooks", Path: "/hooks", Body: `{"payload":"` + payload + `"}`}
+	if res, _ := router.ServeLambda(context.Background(), req); res.StatusCode != 200 {
+		t.Fatalf("Expected 200, saw %d", res.StatusCode)
+	}
+	if body != `{"name":"bob"}` || name != "bob" {
+		t.Errorf("Expected the handler to see the unwrapped body, saw %q and name %v", body, name)
+	}
+
+	req.Body = "not an envelope"
+	if res, _ := router.ServeLambda(context.Background(), req); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a body the middleware rejects, saw %d", res.StatusCode)
+	}
+}
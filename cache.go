@@ -0,0 +1,159 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// NotModifiedSince reports whether the If-Modified-Since header of req shows that
+// the client already has the version of the resource changed at lastModified, in
+// which case the handler can answer with 304 Not Modified. A missing or unparsable
+// header always reports false.
+func NotModifiedSince(req events.APIGatewayProxyRequest, lastModified time.Time) bool {
+	value, ok := headerValue(req.Headers, "If-Modified-Since")
+	if !ok || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(value)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have a one second resolution.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// WithLastModified wraps h so that conditional requests for a resource that did not
+// change since the If-Modified-Since date get a 304 Not Modified without calling h.
+// Other responses of h get a Last-Modified header unless they already have one.
+func WithLastModified(h HandlerFunc, lastModified func(events.APIGatewayProxyRequest) time.Time) HandlerFunc {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		modified := lastModified(req)
+		if NotModifiedSince(req, modified) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotModified,
+				Headers: map[string]string{
+					"Last-Modified": modified.UTC().Format(http.TimeFormat),
+				},
+			}, nil
+		}
+
+		res, err := h(ctx, req)
+		if err != nil || modified.IsZero() {
+			return res, err
+		}
+		if _, ok := headerValue(res.Headers, "Last-Modified"); !ok {
+			if res.Headers == nil {
+				res.Headers = map[string]string{}
+			}
+			res.Headers["Last-Modified"] = modified.UTC().Format(http.TimeFormat)
+		}
+		return res, err
+	}
+}
+
+// Conditional returns a middleware evaluating the conditional request headers
+// If-Match, If-Unmodified-Since, If-None-Match and If-Modified-Since before the
+// handler runs, following the precedence of RFC 7232. Failed preconditions are
+// answered with 304 Not Modified for GET and HEAD requests and 412 Precondition
+// Failed otherwise, without calling the handler.
+//
+// etag and lastModified compute the current validators of the requested resource,
+// and should be cheap. Either may be nil, and an empty ETag or a zero time means
+// the resource has no such validator. Responses of the handler get the ETag and
+// Last-Modified headers unless they already have them.
+func Conditional(etag func(events.APIGatewayProxyRequest) string, lastModified func(events.APIGatewayProxyRequest) time.Time) func(HandlerFunc) HandlerFunc {
+	return func(h HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			var tag string
+			var modified time.Time
+			if etag != nil {
+				tag = etag(req)
+			}
+			if lastModified != nil {
+				modified = lastModified(req)
+			}
+
+			validators := map[string]string{}
+			if tag != "" {
+				validators["ETag"] = tag
+			}
+			if !modified.IsZero() {
+				validators["Last-Modified"] = modified.UTC().Format(http.TimeFormat)
+			}
+
+			if status := checkPreconditions(req, tag, modified); status != 0 {
+				return events.APIGatewayProxyResponse{StatusCode: status, Headers: validators}, nil
+			}
+
+			res, err := h(ctx, req)
+			if err != nil {
+				return res, err
+			}
+			for name, value := range validators {
+				if _, ok := headerValue(res.Headers, name); !ok {
+					if res.Headers == nil {
+						res.Headers = map[string]string{}
+					}
+					res.Headers[name] = value
+				}
+			}
+			return res, err
+		}
+	}
+}
+
+// checkPreconditions returns the status answering req when one of its conditional
+// headers fails, or 0 when the request must be served.
+func checkPreconditions(req events.APIGatewayProxyRequest, tag string, modified time.Time) int {
+	safe := req.HTTPMethod == "GET" || req.HTTPMethod == "HEAD"
+
+	if match, ok := headerValue(req.Headers, "If-Match"); ok {
+		if !etagMatches(match, tag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if value, ok := headerValue(req.Headers, "If-Unmodified-Since"); ok && !modified.IsZero() {
+		if since, err := http.ParseTime(value); err == nil && modified.Truncate(time.Second).After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if match, ok := headerValue(req.Headers, "If-None-Match"); ok {
+		if !etagMatches(match, tag, true) {
+			return 0
+		}
+		if safe {
+			return http.StatusNotModified
+		}
+		return http.StatusPreconditionFailed
+	}
+	if safe && NotModifiedSince(req, modified) {
+		return http.StatusNotModified
+	}
+	return 0
+}
+
+// etagMatches reports whether tag is in the comma separated list of entity tags
+// of a conditional header. The weak comparison ignores the W/ prefix.
+func etagMatches(list, tag string, weak bool) bool {
+	if tag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+				return true
+			}
+		} else if candidate == tag && !strings.HasPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
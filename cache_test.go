@@ -0,0 +1,120 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestWithLastModified(t *testing.T) {
+	modified := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	var calls int
+	h := WithLastModified(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		calls++
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: "hello"}, nil
+	}, func(events.APIGatewayProxyRequest) time.Time { return modified })
+
+	res, _ := h(context.Background(), events.APIGatewayProxyRequest{})
+	if res.StatusCode != 200 || calls != 1 {
+		t.Errorf("Fresh request expected status 200 from the handler, saw %d after %d calls", res.StatusCode, calls)
+	}
+	if res.Headers["Last-Modified"] != "Sun, 01 Mar 2020 12:00:00 GMT" {
+		t.Errorf("Expected a Last-Modified header, saw %q", res.Headers["Last-Modified"])
+	}
+
+	req := events.APIGatewayProxyRequest{Headers: map[string]string{
+		"if-modified-since": modified.Add(time.Hour).Format(http.TimeFormat),
+	}}
+	res, _ = h(context.Background(), req)
+	if res.StatusCode != http.StatusNotModified || calls != 1 {
+		t.Errorf("Conditional request expected status 304 without calling the handler, saw %d after %d calls", res.StatusCode, calls)
+	}
+
+	req.Headers["if-modified-since"] = modified.Add(-time.Hour).Format(http.TimeFormat)
+	res, _ = h(context.Background(), req)
+	if res.StatusCode != 200 || calls != 2 {
+		t.Errorf("Stale conditional request expected status 200, saw %d after %d calls", res.StatusCode, calls)
+	}
+}
+
+func TestNotModifiedSince(t *testing.T) {
+	modified := time.Date(2020, 3, 1, 12, 0, 0, 500, time.UTC)
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{"", false},
+		{"not a date", false},
+		{"Sun, 01 Mar 2020 12:00:00 GMT", true},
+		{"Sun, 01 Mar 2020 11:59:59 GMT", false},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{Headers: map[string]string{}}
+		if test.header != "" {
+			req.Headers["If-Modified-Since"] = test.header
+		}
+		if got := NotModifiedSince(req, modified); got != test.expected {
+			t.Errorf("If-Modified-Since %q expected %v, saw %v", test.header, test.expected, got)
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	modified := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	var called bool
+	h := Conditional(
+		func(events.APIGatewayProxyRequest) string { return `"v1"` },
+		func(events.APIGatewayProxyRequest) time.Time { return modified },
+	)(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	tests := []struct {
+		method   string
+		headers  map[string]string
+		expected int
+	}{
+		{"GET", nil, 200},
+		{"GET", map[string]string{"If-None-Match": `"v1"`}, 304},
+		{"GET", map[string]string{"If-None-Match": `"v0", W/"v1"`}, 304},
+		{"GET", map[string]string{"If-None-Match": "*"}, 304},
+		{"GET", map[string]string{"If-None-Match": `"v0"`}, 200},
+		{"PUT", map[string]string{"If-None-Match": `"v1"`}, 412},
+		{"PUT", map[string]string{"If-None-Match": `"v0"`}, 200},
+		{"GET", map[string]string{"If-Modified-Since": after}, 304},
+		{"GET", map[string]string{"If-Modified-Since": before}, 200},
+		// If-Modified-Since only applies to GET and HEAD.
+		{"POST", map[string]string{"If-Modified-Since": after}, 200},
+		// If-None-Match takes precedence over If-Modified-Since.
+		{"GET", map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": after}, 200},
+		{"PUT", map[string]string{"If-Match": `"v1"`}, 200},
+		{"PUT", map[string]string{"If-Match": `"v0"`}, 412},
+		{"PUT", map[string]string{"If-Match": `W/"v1"`}, 412},
+		{"GET", map[string]string{"If-Match": `"v0"`}, 412},
+		{"PUT", map[string]string{"If-Unmodified-Since": after}, 200},
+		{"PUT", map[string]string{"If-Unmodified-Since": before}, 412},
+		// If-Match takes precedence over If-Unmodified-Since.
+		{"PUT", map[string]string{"If-Match": `"v1"`, "If-Unmodified-Since": before}, 200},
+	}
+	for _, test := range tests {
+		called = false
+		req := events.APIGatewayProxyRequest{HTTPMethod: test.method, Headers: test.headers}
+		res, _ := h(context.Background(), req)
+		if res.StatusCode != test.expected {
+			t.Errorf("%s %v expected status %d, saw %d", test.method, test.headers, test.expected, res.StatusCode)
+		}
+		if called != (test.expected == 200) {
+			t.Errorf("%s %v expected handler called to be %v", test.method, test.headers, test.expected == 200)
+		}
+		if res.Headers["ETag"] != `"v1"` || res.Headers["Last-Modified"] == "" {
+			t.Errorf("%s %v expected validator headers, saw %v", test.method, test.headers, res.Headers)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type canonicalHost struct {
+	host      string
+	httpsOnly bool
+}
+
+// SetCanonicalHost redirects the requests sent to another host than host, such as
+// www.example.com when host is example.com, to the same path and query on host.
+// When httpsOnly is set, the requests made over plain HTTP are redirected to HTTPS
+// too. An empty host only enforces the scheme, and calling it with an empty host
+// and httpsOnly unset removes the redirect. The status code follows
+// TreeMux.RedirectBehavior and SetRedirectMethodBehavior, and UseHandler serves
+// the request as is.
+func (t *TreeMux) SetCanonicalHost(host string, httpsOnly bool) {
+	if host == "" && !httpsOnly {
+		t.canonicalHost = nil
+		return
+	}
+	t.canonicalHost = &canonicalHost{host: host, httpsOnly: httpsOnly}
+}
+
+// canonicalRedirect returns the redirect of req to the canonical host and scheme,
+// or false when req already uses them. Requests without a Host header are only
+// checked for the scheme.
+func (t *TreeMux) canonicalRedirect(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, bool) {
+	c := t.canonicalHost
+	if c == nil {
+		return events.APIGatewayProxyResponse{}, false
+	}
+	host, _ := headerValue(req.Headers, "Host")
+	tls := IsTLS(req)
+	wrongHost := c.host != "" && host != "" && !strings.EqualFold(host, c.host)
+	if !wrongHost && (tls || !c.httpsOnly) {
+		return events.APIGatewayProxyResponse{}, false
+	}
+	code, ok := t.redirectStatusCode(req.HTTPMethod)
+	if !ok {
+		return events.APIGatewayProxyResponse{}, false
+	}
+
+	target := url.URL{Scheme: "http", Host: host, Path: req.Path, RawQuery: RawQueryString(ctx, req)}
+	if tls || c.httpsOnly {
+		target.Scheme = "https"
+	}
+	if c.host != "" {
+		target.Host = c.host
+	}
+	res, _ := LambdaRedirect(ctx, req, target.String(), code)
+	return res, true
+}
@@ -0,0 +1,64 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	router := New()
+	router.GET("/users", simpleHandler)
+	router.SetCanonicalHost("example.com", false)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "http://www.example.com/prod/users?page=2", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "http://example.com/prod/users?page=2" {
+		t.Errorf("Expected a 301 to the apex domain, saw %d to %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "http://example.com/prod/users", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected the canonical host to be served, saw %d", w.Code)
+	}
+}
+
+func TestCanonicalHostHTTPS(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/users", simpleHandler)
+	router.POST("/users", simpleHandler)
+	router.SetCanonicalHost("", true)
+	router.SetRedirectMethodBehavior("POST", Redirect308)
+
+	serve := func(method, proto string) events.APIGatewayProxyResponse {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod:            method,
+			Resource:              "/users",
+			Path:                  "/users",
+			Headers:               map[string]string{"Host": "api.example.com", "X-Forwarded-Proto": proto},
+			QueryStringParameters: map[string]string{"q": "a b"},
+		})
+		return res
+	}
+
+	if res := serve("GET", "http"); res.StatusCode != http.StatusMovedPermanently || res.Headers["Location"] != "https://api.example.com/users?q=a+b" {
+		t.Errorf("Expected a 301 to HTTPS, saw %d to %q", res.StatusCode, res.Headers["Location"])
+	}
+	if res := serve("POST", "http"); res.StatusCode != http.StatusPermanentRedirect {
+		t.Errorf("Expected the redirect status of POST, saw %d", res.StatusCode)
+	}
+	if res := serve("GET", "https"); res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected HTTPS requests to be served, saw %d", res.StatusCode)
+	}
+
+	router.SetCanonicalHost("", false)
+	if res := serve("GET", "http"); res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected no redirect once removed, saw %d", res.StatusCode)
+	}
+}
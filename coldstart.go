@@ -0,0 +1,37 @@
+package lambdarouter
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// warm is set by the first request served with ServeLambda in the process.
+var warm int32
+
+// IsColdStart reports whether the request of ctx is the first one served with
+// ServeLambda by the process, and so paid for the start of the Lambda environment.
+func IsColdStart(ctx context.Context) bool {
+	cold, _ := ctx.Value(coldStartContextKey).(bool)
+	return cold
+}
+
+// EnableColdStartHeader adds an X-Cold-Start: true header to the response of the
+// request which started the process. See IsColdStart.
+func (t *TreeMux) EnableColdStartHeader() {
+	t.coldStartHeader = true
+}
+
+// markColdStart records in ctx whether this is the first request of the process.
+func markColdStart(ctx context.Context) (context.Context, bool) {
+	cold := atomic.CompareAndSwapInt32(&warm, 0, 1)
+	return context.WithValue(ctx, coldStartContextKey, cold), cold
+}
+
+func setColdStartHeader(res *events.APIGatewayProxyResponse) {
+	if res.Headers == nil {
+		res.Headers = map[string]string{}
+	}
+	res.Headers["X-Cold-Start"] = "true"
+}
@@ -0,0 +1,56 @@
+package lambdarouter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestColdStart(t *testing.T) {
+	defer atomic.StoreInt32(&warm, atomic.LoadInt32(&warm))
+	atomic.StoreInt32(&warm, 0)
+
+	var cold []bool
+	router := newLambdaRouter()
+	router.EnableColdStartHeader()
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		cold = append(cold, IsColdStart(ctx))
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/abc", Path: "/abc"}
+	first, _ := router.ServeLambda(context.Background(), req)
+	second, _ := router.ServeLambda(context.Background(), req)
+
+	if len(cold) != 2 || !cold[0] || cold[1] {
+		t.Errorf("Expected only the first invocation to be a cold start, saw %v", cold)
+	}
+	if first.Headers["X-Cold-Start"] != "true" {
+		t.Errorf("Expected the first response to be flagged, saw %v", first.Headers)
+	}
+	if _, ok := second.Headers["X-Cold-Start"]; ok {
+		t.Errorf("Expected the second response not to be flagged, saw %v", second.Headers)
+	}
+	if IsColdStart(context.Background()) {
+		t.Error("Expected no cold start outside of a request")
+	}
+}
+
+func TestColdStartPanic(t *testing.T) {
+	defer atomic.StoreInt32(&warm, atomic.LoadInt32(&warm))
+	atomic.StoreInt32(&warm, 0)
+
+	router := newLambdaRouter()
+	router.EnableColdStartHeader()
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		panic("oops")
+	})
+
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/abc", Path: "/abc"}
+	res, _ := router.ServeLambda(context.Background(), req)
+	if res.StatusCode != 500 || res.Headers["X-Cold-Start"] != "true" {
+		t.Errorf("Expected the response of the panic handler to be flagged, saw %d %v", res.StatusCode, res.Headers)
+	}
+}
@@ -0,0 +1,50 @@
+package lambdarouter
+
+// ConcurrencyPolicy decides what happens to requests beyond the limit set with
+// SetMaxConcurrency.
+type ConcurrencyPolicy int
+
+const (
+	// QueueExcess makes excess requests wait until a handler finishes.
+	QueueExcess ConcurrencyPolicy = iota
+	// RejectExcess answers excess requests with 429 Too Many Requests, like Lambda
+	// does once its concurrency limit is reached.
+	RejectExcess
+)
+
+// SetMaxConcurrency limits the number of handlers running at the same time in local
+// ServeHTTP mode to n, which helps reproducing Lambda throttling while testing.
+// Requests beyond the limit are queued or rejected according to policy. A value of
+// 0 removes the limit. It must be called before serving requests.
+func (t *TreeMux) SetMaxConcurrency(n int, policy ConcurrencyPolicy) {
+	t.concurrencyPolicy = policy
+	if n <= 0 {
+		t.concurrency = nil
+		return
+	}
+	t.concurrency = make(chan struct{}, n)
+}
+
+// acquireSlot reserves a handler slot, and reports false when the request must be
+// rejected instead. Every successful call must be followed by releaseSlot.
+func (t *TreeMux) acquireSlot() bool {
+	if t.concurrency == nil {
+		return true
+	}
+	if t.concurrencyPolicy == RejectExcess {
+		select {
+		case t.concurrency <- struct{}{}:
+			return true
+		default:
+			return false
+		}
+	}
+	t.concurrency <- struct{}{}
+	return true
+}
+
+func (t *TreeMux) releaseSlot() {
+	if t.concurrency != nil {
+		<-t.concurrency
+	}
+}
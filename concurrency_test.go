@@ -0,0 +1,138 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func blockingRouter(started chan<- struct{}, release <-chan struct{}, inFlight, maxInFlight *int32) *TreeMux {
+	router := New()
+	router.GET("/slow", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		n := atomic.AddInt32(inFlight, 1)
+		for {
+			max := atomic.LoadInt32(maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+				break
+			}
+		}
+		started <- struct{}{}
+		<-release
+		atomic.AddInt32(inFlight, -1)
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	return router
+}
+
+func serveSlow(router *TreeMux) int {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/__stage__/slow", nil)
+	router.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestMaxConcurrencyReject(t *testing.T) {
+	var inFlight, maxInFlight int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	router := blockingRouter(started, release, &inFlight, &maxInFlight)
+	router.SetMaxConcurrency(2, RejectExcess)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = serveSlow(router)
+		}(i)
+	}
+	<-started
+	<-started
+
+	for i := 0; i < 3; i++ {
+		if code := serveSlow(router); code != http.StatusTooManyRequests {
+			t.Errorf("Excess request expected status 429, saw %d", code)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	for _, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request within the limit expected status 200, saw %d", code)
+		}
+	}
+}
+
+func TestMaxConcurrencyQueue(t *testing.T) {
+	var inFlight, maxInFlight int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	router := blockingRouter(started, release, &inFlight, &maxInFlight)
+	router.SetMaxConcurrency(1, QueueExcess)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 4)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = serveSlow(router)
+		}(i)
+	}
+	for range codes {
+		<-started
+		release <- struct{}{}
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Queued request expected status 200, saw %d", code)
+		}
+	}
+	if maxInFlight != 1 {
+		t.Errorf("Expected at most 1 handler in flight, saw %d", maxInFlight)
+	}
+}
+
+func TestSafeAddRoutesWhileRunning(t *testing.T) {
+	router := New()
+	router.SafeAddRoutesWhileRunning = true
+	router.GET("/users/:id", simpleHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			path := "/route" + strconv.Itoa(i) + "/:id"
+			router.GET(path, simpleHandler).Name("route" + strconv.Itoa(i))
+			router.NewGroup("/group"+strconv.Itoa(i)).POST("/items", simpleHandler)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/prod/route"+strconv.Itoa(i%10)+"/5", nil)
+		router.ServeHTTP(w, r)
+		router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod: "GET", Resource: "/prod/users/5", Path: "/prod/users/5",
+		})
+		router.Lookup(events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/prod/group1/items"})
+	}
+	<-done
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/prod/group99/items", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected the routes added while serving to be served, saw %d", w.Code)
+	}
+}
@@ -1,11 +1,8 @@
-// +build go1.7
-
 package lambdarouter
 
 import (
 	"context"
-
-	"github.com/aws/aws-lambda-go/events"
+	"net/http"
 )
 
 // ContextGroup is a wrapper around Group, with the purpose of mimicking its API, but with the use of Handle-based handlers.
@@ -48,65 +45,63 @@ func (cg *ContextGroup) NewGroup(path string) *ContextGroup {
 	return cg.NewContextGroup(path)
 }
 
-// Handle allows handling HTTP requests via an Handle, as opposed to an httptreemux.HandlerFunc.
-// Any parameters from the request URL are stored in a map[string]string in the request's context.
-func (cg *ContextGroup) Handle(method, path string, handler HandlerFunc) {
+// Use adds middleware to the wrapped group. See Group.Use.
+func (cg *ContextGroup) Use(mw ...func(HandlerFunc) HandlerFunc) *ContextGroup {
+	cg.group.Use(mw...)
+	return cg
+}
 
-	cg.group.Handle(method, path, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-		// if params != nil {
-		// 	r = r.WithContext(AddParamsToContext(r.Context(), params))
-		// }
-		return handler(ctx, req)
-	})
+// Handle allows handling HTTP requests via an Handle, as opposed to an httptreemux.HandlerFunc.
+// Any parameters from the request URL are stored in a map[string]string in the request's context,
+// as for every route, see ContextParams.
+func (cg *ContextGroup) Handle(method, path string, handler HandlerFunc) *Route {
+	return cg.group.Handle(method, path, handler)
 }
 
 // Handler allows handling HTTP requests via an http.Handler interface, as opposed to an httptreemux.HandlerFunc.
 // Any parameters from the request URL are stored in a map[string]string in the request's context.
-// func (cg *ContextGroup) Handler(method, path string, handler http.Handler) {
-// 	cg.group.Handle(method, path, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-// 		if params != nil {
-// 			r = r.WithContext(AddParamsToContext(r.Context(), params))
-// 		}
-// 		handler.ServeHTTP(w, r)
-// 	})
-// }
+func (cg *ContextGroup) Handler(method, path string, handler http.Handler) *Route {
+	return cg.Handle(method, path, WrapHTTPHandler(handler))
+}
 
 // GET is convenience method for handling GET requests on a context group.
-func (cg *ContextGroup) GET(path string, handler HandlerFunc) {
-	cg.Handle("GET", path, handler)
+func (cg *ContextGroup) GET(path string, handler HandlerFunc) *Route {
+	return cg.Handle("GET", path, handler)
 }
 
 // POST is convenience method for handling POST requests on a context group.
-func (cg *ContextGroup) POST(path string, handler HandlerFunc) {
-	cg.Handle("POST", path, handler)
+func (cg *ContextGroup) POST(path string, handler HandlerFunc) *Route {
+	return cg.Handle("POST", path, handler)
 }
 
 // PUT is convenience method for handling PUT requests on a context group.
-func (cg *ContextGroup) PUT(path string, handler HandlerFunc) {
-	cg.Handle("PUT", path, handler)
+func (cg *ContextGroup) PUT(path string, handler HandlerFunc) *Route {
+	return cg.Handle("PUT", path, handler)
 }
 
 // DELETE is convenience method for handling DELETE requests on a context group.
-func (cg *ContextGroup) DELETE(path string, handler HandlerFunc) {
-	cg.Handle("DELETE", path, handler)
+func (cg *ContextGroup) DELETE(path string, handler HandlerFunc) *Route {
+	return cg.Handle("DELETE", path, handler)
 }
 
 // PATCH is convenience method for handling PATCH requests on a context group.
-func (cg *ContextGroup) PATCH(path string, handler HandlerFunc) {
-	cg.Handle("PATCH", path, handler)
+func (cg *ContextGroup) PATCH(path string, handler HandlerFunc) *Route {
+	return cg.Handle("PATCH", path, handler)
 }
 
 // HEAD is convenience method for handling HEAD requests on a context group.
-func (cg *ContextGroup) HEAD(path string, handler HandlerFunc) {
-	cg.Handle("HEAD", path, handler)
+func (cg *ContextGroup) HEAD(path string, handler HandlerFunc) *Route {
+	return cg.Handle("HEAD", path, handler)
 }
 
 // OPTIONS is convenience method for handling OPTIONS requests on a context group.
-func (cg *ContextGroup) OPTIONS(path string, handler HandlerFunc) {
-	cg.Handle("OPTIONS", path, handler)
+func (cg *ContextGroup) OPTIONS(path string, handler HandlerFunc) *Route {
+	return cg.Handle("OPTIONS", path, handler)
 }
 
 // ContextParams returns the params map associated with the given context if one exists. Otherwise, an empty map is returned.
+// The router stores the path parameters of the matched route in the context of its handler, so they are the same as the
+// PathParameters of the request.
 func ContextParams(ctx context.Context) map[string]string {
 	if p, ok := ctx.Value(paramsContextKey).(map[string]string); ok {
 		return p
@@ -114,6 +109,23 @@ func ContextParams(ctx context.Context) map[string]string {
 	return map[string]string{}
 }
 
+// MatchedGroup returns the group the route being served was registered on, or nil
+// when the context does not come from a matched route.
+func MatchedGroup(ctx context.Context) *Group {
+	g, _ := ctx.Value(groupContextKey).(*Group)
+	return g
+}
+
+// PublicPath returns the path of the request being served with ctx as clients see
+// it on API Gateway, such as /hello/bob. When serving locally, req.Path starts with
+// the stage segment, as in /prod/hello/bob, which PublicPath leaves out. It is the
+// path before any rewrite of Group.RewriteTo, and empty when the context does not
+// come from a matched route.
+func PublicPath(ctx context.Context) string {
+	path, _ := ctx.Value(publicPathContextKey).(string)
+	return path
+}
+
 // AddParamsToContext inserts a parameters map into a context using
 // the package's internal context key. Clients of this package should
 // really only use this for unit tests.
@@ -123,5 +135,19 @@ func AddParamsToContext(ctx context.Context, params map[string]string) context.C
 
 type contextKey int
 
-// paramsContextKey is used to retrieve a path's params map from a request's context.
-const paramsContextKey contextKey = 0
+const (
+	// paramsContextKey is used to retrieve a path's params map from a request's context.
+	paramsContextKey contextKey = iota
+	// jsonBodyContextKey is used to retrieve the lazily decoded JSON body of a request.
+	jsonBodyContextKey
+	// groupContextKey is used to retrieve the group of the matched route.
+	groupContextKey
+	// rawQueryContextKey is used to retrieve the query string of a request served locally.
+	rawQueryContextKey
+	// bodyReaderContextKey is used to retrieve the unread body of a request served locally.
+	bodyReaderContextKey
+	// coldStartContextKey is used to tell whether a request started the process.
+	coldStartContextKey
+	// publicPathContextKey is used to retrieve the path of a request without the local stage.
+	publicPathContextKey
+)
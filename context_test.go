@@ -1,24 +1,24 @@
-// +build go1.7
-
 package lambdarouter
 
 import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
 type IContextGroup interface {
-	GET(path string, handler HandlerFunc)
-	POST(path string, handler HandlerFunc)
-	PUT(path string, handler HandlerFunc)
-	PATCH(path string, handler HandlerFunc)
-	DELETE(path string, handler HandlerFunc)
-	HEAD(path string, handler HandlerFunc)
-	OPTIONS(path string, handler HandlerFunc)
+	GET(path string, handler HandlerFunc) *Route
+	POST(path string, handler HandlerFunc) *Route
+	PUT(path string, handler HandlerFunc) *Route
+	PATCH(path string, handler HandlerFunc) *Route
+	DELETE(path string, handler HandlerFunc) *Route
+	HEAD(path string, handler HandlerFunc) *Route
+	OPTIONS(path string, handler HandlerFunc) *Route
 
 	NewContextGroup(path string) *ContextGroup
 	NewGroup(path string) *ContextGroup
@@ -38,6 +38,72 @@ func TestContextParams(t *testing.T) {
 	}
 }
 
+func TestPublicPath(t *testing.T) {
+	var seen, rewritten string
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		seen, rewritten = PublicPath(ctx), req.Path
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+	}
+
+	router := New()
+	router.GET("/", handler)
+	router.GET("/hello/:name", handler)
+	router.NewGroup("/v2").RewriteTo("/internal").GET("/users", handler)
+
+	tests := []struct {
+		path, public, handlerPath string
+	}{
+		{"/prod/hello/bob", "/hello/bob", "/prod/hello/bob"},
+		{"/prod/", "/", "/prod/"},
+		{"/prod/v2/users", "/v2/users", "/prod/internal/users"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", test.path, nil)
+		router.ServeHTTP(w, r)
+		if seen != test.public || rewritten != test.handlerPath {
+			t.Errorf("%s expected public path %s and path %s, saw %s and %s", test.path, test.public, test.handlerPath, seen, rewritten)
+		}
+	}
+
+	lambdaRouter := newLambdaRouter()
+	lambdaRouter.GET("/hello/:name", handler)
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/hello/{name}", Path: "/hello/bob"}
+	req.RequestContext.Stage = "prod"
+	lambdaRouter.ServeLambda(context.Background(), req)
+	if seen != "/hello/bob" {
+		t.Errorf("Expected /hello/bob on Lambda, saw %s", seen)
+	}
+}
+
+func TestContextParamsInHandler(t *testing.T) {
+	var params map[string]string
+	var value interface{}
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		params, value = ContextParams(ctx), ctx.Value("abc")
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+	expected := map[string]string{"id": "5", "path": "a/b"}
+
+	router := New()
+	router.DefaultContext = context.WithValue(context.Background(), "abc", "def")
+	router.GET("/users/:id/*path", handler)
+	r, _ := http.NewRequest("GET", "/__stage__/users/5/a/b", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if !reflect.DeepEqual(params, expected) || value != "def" {
+		t.Errorf("ServeHTTP expected params %v and value def, saw %v and %v", expected, params, value)
+	}
+
+	params, value = nil, nil
+	lambda := newLambdaRouter()
+	lambda.DefaultContext = router.DefaultContext
+	lambda.GET("/users/:id/*path", handler)
+	lambda.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/users/5/a/b", Path: "/users/5/a/b"})
+	if !reflect.DeepEqual(params, expected) || value != "def" {
+		t.Errorf("ServeLambda expected params %v and value def, saw %v and %v", expected, params, value)
+	}
+}
+
 func TestContextGroupMethods(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Log(scenario.description)
@@ -177,100 +243,201 @@ func (f ContextGroupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// func TestNewContextGroupHandler(t *testing.T) {
-// 	router := New()
-// 	group := router.NewGroup("/api")
-
-// 	group.UsingContext().Handler("GET", "/v1", ContextGroupHandler{})
-
-// 	tests := []struct {
-// 		uri, expected string
-// 	}{
-// 		{"/api/v1", "200 OK GET /api/v1"},
-// 	}
-
-// 	for _, tc := range tests {
-// 		r, err := http.NewRequest("GET", tc.uri, nil)
-// 		if err != nil {
-// 			t.Fatal(err)
-// 		}
-
-// 		w := httptest.NewRecorder()
-// 		router.ServeHTTP(w, r)
-
-// 		if w.Code != http.StatusOK {
-// 			t.Errorf("GET %s: expected %d, but got %d", tc.uri, http.StatusOK, w.Code)
-// 		}
-// 		if got := w.Body.String(); got != tc.expected {
-// 			t.Errorf("GET %s : expected %q, but got %q", tc.uri, tc.expected, got)
-// 		}
-// 	}
-// }
-
-// func TestDefaultContext(t *testing.T) {
-// 	router := New()
-// 	ctx := context.WithValue(context.Background(), "abc", "def")
-// 	expectContext := false
-
-// 	router.GET("/abc",  func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-// 		contextValue := r.Context().Value("abc")
-// 		if expectContext {
-// 			x, ok := contextValue.(string)
-// 			if !ok || x != "def" {
-// 				t.Errorf("Unexpected context key value: %+v", contextValue)
-// 			}
-// 		} else {
-// 			if contextValue != nil {
-// 				t.Errorf("Expected blank context but key had value %+v", contextValue)
-// 			}
-// 		}
-// 	})
-
-// 	r, err := http.NewRequest("GET", "/abc", nil)
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	w := httptest.NewRecorder()
-// 	t.Log("Testing without DefaultContext")
-// 	router.ServeHTTP(w, r)
-
-// 	router.DefaultContext = ctx
-// 	expectContext = true
-// 	w = httptest.NewRecorder()
-// 	t.Log("Testing with DefaultContext")
-// 	router.ServeHTTP(w, r)
-// }
-
-// func TestContextMuxSimple(t *testing.T) {
-// 	router := NewContextMux()
-// 	ctx := context.WithValue(context.Background(), "abc", "def")
-// 	expectContext := false
-
-// 	router.GET("/abc", func(w http.ResponseWriter, r *http.Request) {
-// 		contextValue := r.Context().Value("abc")
-// 		if expectContext {
-// 			x, ok := contextValue.(string)
-// 			if !ok || x != "def" {
-// 				t.Errorf("Unexpected context key value: %+v", contextValue)
-// 			}
-// 		} else {
-// 			if contextValue != nil {
-// 				t.Errorf("Expected blank context but key had value %+v", contextValue)
-// 			}
-// 		}
-// 	})
-
-// 	r, err := http.NewRequest("GET", "/abc", nil)
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	w := httptest.NewRecorder()
-// 	t.Log("Testing without DefaultContext")
-// 	router.ServeHTTP(w, r)
-
-// 	router.DefaultContext = ctx
-// 	expectContext = true
-// 	w = httptest.NewRecorder()
-// 	t.Log("Testing with DefaultContext")
-// 	router.ServeHTTP(w, r)
-// }
+func TestNewContextGroupHandler(t *testing.T) {
+	router := New()
+	group := router.NewGroup("/api")
+
+	group.UsingContext().Handler("GET", "/v1", ContextGroupHandler{})
+
+	tests := []struct {
+		uri, expected string
+	}{
+		{"/__stage__/api/v1", "200 OK GET /api/v1"},
+	}
+
+	for _, tc := range tests {
+		r, err := http.NewRequest("GET", tc.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected %d, but got %d", tc.uri, http.StatusOK, w.Code)
+		}
+		if got := w.Body.String(); got != tc.expected {
+			t.Errorf("GET %s : expected %q, but got %q", tc.uri, tc.expected, got)
+		}
+	}
+}
+
+type headerHandler struct{}
+
+func (headerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Id", ContextParams(r.Context())["id"])
+	w.Header().Add("X-Query", r.URL.Query().Get("q"))
+	w.Header().Add("Set-Cookie", "a=1")
+	w.Header().Add("Set-Cookie", "b=2")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(r.Method))
+}
+
+func TestContextGroupHandlerHeaders(t *testing.T) {
+	router := New()
+	group := router.NewGroup("/api").UsingContext()
+	group.Handler("PUT", "/users/:id", headerHandler{})
+	group.Handler("POST", "/v1", ContextGroupHandler{})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("PUT", "/__stage__/api/users/5?q=abc", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("PUT expected %d, but got %d", http.StatusCreated, w.Code)
+	}
+	if got := w.Header().Get("X-Id"); got != "5" {
+		t.Errorf("Expected X-Id header 5 from the path parameters, but got %q", got)
+	}
+	if got := w.Header().Get("X-Query"); got != "abc" {
+		t.Errorf("Expected X-Query header abc, but got %q", got)
+	}
+	if got := w.Header()["Set-Cookie"]; !reflect.DeepEqual(got, []string{"a=1", "b=2"}) {
+		t.Errorf("Expected the two cookies as separate headers, but got %q", got)
+	}
+	if got := w.Body.String(); got != "PUT" {
+		t.Errorf("Expected body PUT, but got %q", got)
+	}
+
+	res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "PUT", Path: "/__stage__/api/users/5"})
+	if got := res.MultiValueHeaders["Set-Cookie"]; !reflect.DeepEqual(got, []string{"a=1", "b=2"}) {
+		t.Errorf("Expected the two cookies in MultiValueHeaders, but got %q", got)
+	}
+	if _, ok := res.Headers["Set-Cookie"]; ok {
+		t.Errorf("Expected no joined Set-Cookie in Headers, but got %q", res.Headers["Set-Cookie"])
+	}
+
+	// The wrapped handler decides about the methods it supports.
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/__stage__/api/v1", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST expected %d, but got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestDefaultContext(t *testing.T) {
+	router := New()
+	ctx := context.WithValue(context.Background(), "abc", "def")
+	expectContext := false
+	called := 0
+
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called++
+		contextValue := ctx.Value("abc")
+		if expectContext {
+			x, ok := contextValue.(string)
+			if !ok || x != "def" {
+				t.Errorf("Unexpected context key value: %+v", contextValue)
+			}
+		} else {
+			if contextValue != nil {
+				t.Errorf("Expected blank context but key had value %+v", contextValue)
+			}
+		}
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	r, err := http.NewRequest("GET", "/__stage__/abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	t.Log("Testing without DefaultContext")
+	router.ServeHTTP(w, r)
+
+	router.DefaultContext = ctx
+	expectContext = true
+	w = httptest.NewRecorder()
+	t.Log("Testing with DefaultContext")
+	router.ServeHTTP(w, r)
+
+	if called != 2 {
+		t.Errorf("Expected the handler to be called twice, but got %d", called)
+	}
+}
+
+func TestDefaultContextLambda(t *testing.T) {
+	router := newLambdaRouter()
+	router.DefaultContext = context.WithValue(context.Background(), "abc", "def")
+
+	var value, invocation interface{}
+	var hasDeadline bool
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		value, invocation = ctx.Value("abc"), ctx.Value("request")
+		_, hasDeadline = ctx.Deadline()
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), "request", "42"), time.Minute)
+	defer cancel()
+	router.ServeLambda(ctx, events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/abc", Path: "/abc"})
+
+	if value != "def" || invocation != "42" {
+		t.Errorf("Expected values from both contexts, but got %v and %v", value, invocation)
+	}
+	if !hasDeadline {
+		t.Error("Expected the deadline of the invocation context")
+	}
+}
+
+func TestContextMuxSimple(t *testing.T) {
+	router := NewContextMux()
+	ctx := context.WithValue(context.Background(), "abc", "def")
+	expectContext := false
+
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		contextValue := ctx.Value("abc")
+		if expectContext {
+			x, ok := contextValue.(string)
+			if !ok || x != "def" {
+				t.Errorf("Unexpected context key value: %+v", contextValue)
+			}
+		} else {
+			if contextValue != nil {
+				t.Errorf("Expected blank context but key had value %+v", contextValue)
+			}
+		}
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	r, err := http.NewRequest("GET", "/__stage__/abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	t.Log("Testing without DefaultContext")
+	router.ServeHTTP(w, r)
+
+	router.DefaultContext = ctx
+	expectContext = true
+	w = httptest.NewRecorder()
+	t.Log("Testing with DefaultContext")
+	router.ServeHTTP(w, r)
+}
+
+func TestContextMuxRouteOptions(t *testing.T) {
+	router := NewContextMux()
+	router.GET("/logo", simpleHandler).CacheControl("public, max-age=60")
+	router.NewGroup("/api").GET("/users", simpleHandler).Name("users")
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/__stage__/logo", nil)
+	router.ServeHTTP(w, r)
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("Expected the Cache-Control of the route, but got %q", got)
+	}
+	if url, err := router.URLFor("users", nil); err != nil || url != "/api/users" {
+		t.Errorf("Expected the named context group route, but got %q, %v", url, err)
+	}
+}
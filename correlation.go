@@ -0,0 +1,29 @@
+package lambdarouter
+
+import "github.com/aws/aws-lambda-go/events"
+
+// DefaultCorrelationHeaders are the headers echoed by a router returned by New.
+var DefaultCorrelationHeaders = []string{"X-Amzn-Trace-Id", "X-Request-Id", "X-Correlation-Id"}
+
+// SetCorrelationHeaders sets the headers which are copied from each request into
+// its response, so that clients and downstream systems can correlate them. Headers
+// already set by the handler are left untouched. Pass nil to disable the copy.
+func (t *TreeMux) SetCorrelationHeaders(headers []string) {
+	t.correlationHeaders = headers
+}
+
+func (t *TreeMux) echoCorrelationHeaders(req events.APIGatewayProxyRequest, res *events.APIGatewayProxyResponse) {
+	for _, name := range t.correlationHeaders {
+		v, ok := headerValue(req.Headers, name)
+		if !ok {
+			continue
+		}
+		if _, set := headerValue(res.Headers, name); set {
+			continue
+		}
+		if res.Headers == nil {
+			res.Headers = map[string]string{}
+		}
+		res.Headers[name] = v
+	}
+}
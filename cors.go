@@ -0,0 +1,130 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CORSConfig configures the Cross-Origin Resource Sharing headers added by
+// EnableCORS.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API. "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods allowed in preflight responses. When empty,
+	// the methods registered on the requested path are allowed.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed in preflight responses. When
+	// empty, the headers asked for by the browser are allowed.
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization headers. The
+	// origin of the request is then reflected instead of answering "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses. Zero leaves it to
+	// the browser.
+	MaxAge time.Duration
+}
+
+// EnableCORS answers the CORS preflight requests of every path, by setting
+// TreeMux.OptionsHandler, and adds the CORS headers to the responses of the matched
+// routes for the allowed origins. Explicit OPTIONS handlers still take precedence.
+func (t *TreeMux) EnableCORS(cfg CORSConfig) {
+	t.cors = &cfg
+	t.OptionsHandler = t.corsPreflight
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or false
+// when origin is not allowed.
+func (c *CORSConfig) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			if c.AllowCredentials {
+				return origin, true
+			}
+			return "*", true
+		}
+		if allowed == origin {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// setCORSHeaders adds the CORS headers for the origin of req to res, without
+// overriding the ones set by the handler.
+func (t *TreeMux) setCORSHeaders(req events.APIGatewayProxyRequest, res *events.APIGatewayProxyResponse) {
+	origin, _ := headerValue(req.Headers, "Origin")
+	allow, ok := t.cors.allowOrigin(origin)
+	if !ok {
+		return
+	}
+	if _, set := headerValue(res.Headers, "Access-Control-Allow-Origin"); set {
+		return
+	}
+	if res.Headers == nil {
+		res.Headers = map[string]string{}
+	}
+	res.Headers["Access-Control-Allow-Origin"] = allow
+	if allow != "*" {
+		res.Headers["Vary"] = "Origin"
+	}
+	if t.cors.AllowCredentials {
+		res.Headers["Access-Control-Allow-Credentials"] = "true"
+	}
+}
+
+// corsPreflight answers preflight requests. The origin headers are added afterwards
+// by setCORSHeaders, as for any matched route.
+func (t *TreeMux) corsPreflight(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	res := events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent, Headers: map[string]string{}}
+	origin, _ := headerValue(req.Headers, "Origin")
+	if _, ok := t.cors.allowOrigin(origin); !ok {
+		return res, nil
+	}
+
+	methods := strings.Join(t.cors.AllowedMethods, ", ")
+	if methods == "" {
+		methods = t.corsMethods(req.Path)
+	}
+	res.Headers["Access-Control-Allow-Methods"] = methods
+
+	headers := strings.Join(t.cors.AllowedHeaders, ", ")
+	if headers == "" {
+		headers, _ = headerValue(req.Headers, "Access-Control-Request-Headers")
+	}
+	if headers != "" {
+		res.Headers["Access-Control-Allow-Headers"] = headers
+	}
+	if t.cors.MaxAge > 0 {
+		res.Headers["Access-Control-Max-Age"] = strconv.Itoa(int(t.cors.MaxAge / time.Second))
+	}
+	return res, nil
+}
+
+// corsMethods returns the methods registered on the route matching path.
+func (t *TreeMux) corsMethods(path string) string {
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+
+	if path == "" {
+		// As in lookup, for the integrations sending no path at all.
+		path = "/"
+	}
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	}
+	n, _, _ := t.root.search("", path[1:])
+	if n == nil {
+		return ""
+	}
+	return strings.Replace(t.allowedMethods(n), " ", ", ", -1)
+}
@@ -0,0 +1,103 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/users/:id", simpleHandler)
+	router.PUT("/users/:id", simpleHandler)
+	router.EnableCORS(CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	preflight := func(origin string) events.APIGatewayProxyResponse {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod: "OPTIONS",
+			Resource:   "/users/5",
+			Path:       "/users/5",
+			Headers: map[string]string{
+				"Origin":                         origin,
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "Content-Type, Authorization",
+			},
+		})
+		return res
+	}
+
+	res := preflight("https://app.example.com")
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, HEAD, PUT",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "600",
+		"Vary":                         "Origin",
+	}
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected preflight status 204, saw %d", res.StatusCode)
+	}
+	for key, value := range expected {
+		if res.Headers[key] != value {
+			t.Errorf("Expected %s: %s, saw %q", key, value, res.Headers[key])
+		}
+	}
+
+	res = preflight("https://evil.example.com")
+	if _, ok := res.Headers["Access-Control-Allow-Origin"]; ok {
+		t.Errorf("Expected no CORS headers for an unknown origin, saw %v", res.Headers)
+	}
+}
+
+func TestCORSPreflightEmptyPath(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/", simpleHandler)
+	router.EnableCORS(CORSConfig{AllowedOrigins: []string{"*"}})
+
+	res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: "OPTIONS",
+		Headers:    map[string]string{"Origin": "https://app.example.com"},
+	})
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected preflight status 204 without a path, saw %d", res.StatusCode)
+	}
+	if methods := res.Headers["Access-Control-Allow-Methods"]; methods != "GET, HEAD" {
+		t.Errorf("Expected the methods of / without a path, saw %q", methods)
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/users/:id", simpleHandler)
+
+	get := func(origin string) events.APIGatewayProxyResponse {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod: "GET",
+			Resource:   "/users/5",
+			Path:       "/users/5",
+			Headers:    map[string]string{"Origin": origin},
+		})
+		return res
+	}
+
+	router.EnableCORS(CORSConfig{AllowedOrigins: []string{"*"}})
+	if res := get("https://app.example.com"); res.Headers["Access-Control-Allow-Origin"] != "*" {
+		t.Errorf("Expected any origin to be allowed, saw %v", res.Headers)
+	}
+
+	// Credentials can not be used with the wildcard, so the origin is echoed.
+	router.EnableCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	res := get("https://app.example.com")
+	if res.Headers["Access-Control-Allow-Origin"] != "https://app.example.com" || res.Headers["Access-Control-Allow-Credentials"] != "true" {
+		t.Errorf("Expected the origin to be echoed with credentials, saw %v", res.Headers)
+	}
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected the handler to be called, saw %d", res.StatusCode)
+	}
+}
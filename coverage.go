@@ -0,0 +1,52 @@
+package lambdarouter
+
+import (
+	"sort"
+	"sync"
+)
+
+// routeCoverage records which routes served requests.
+type routeCoverage struct {
+	mutex sync.Mutex
+	hits  map[*Route]bool
+}
+
+// EnableCoverageTracking makes the router record which routes serve requests, so
+// that CoverageReport can tell the ones a test suite never reached. It is meant for
+// tests and must be called before serving requests.
+func (t *TreeMux) EnableCoverageTracking() {
+	t.coverage = &routeCoverage{hits: make(map[*Route]bool)}
+}
+
+// CoverageReport returns the registered routes which did not serve any request
+// since EnableCoverageTracking was called, as sorted "METHOD pattern" strings such
+// as "GET /users/:id". It returns nil when tracking is not enabled.
+func (t *TreeMux) CoverageReport() []string {
+	if t.coverage == nil {
+		return nil
+	}
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+	t.coverage.mutex.Lock()
+	defer t.coverage.mutex.Unlock()
+
+	report := []string{}
+	t.root.walk(func(n *node) {
+		n.eachRoute(func(method string, route *Route) {
+			// Skip the HEAD routes added implicitly for GET routes.
+			if route.method == method && !t.coverage.hits[route] {
+				report = append(report, method+" "+route.path)
+			}
+		})
+	})
+	sort.Strings(report)
+	return report
+}
+
+func (c *routeCoverage) hit(route *Route) {
+	c.mutex.Lock()
+	c.hits[route] = true
+	c.mutex.Unlock()
+}
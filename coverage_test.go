@@ -0,0 +1,36 @@
+package lambdarouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCoverageReport(t *testing.T) {
+	router := New()
+	router.GET("/users", simpleHandler)
+	router.GET("/users/:id", simpleHandler)
+	router.DELETE("/users/:id", simpleHandler)
+
+	if report := router.CoverageReport(); report != nil {
+		t.Errorf("Expected no report without tracking, saw %v", report)
+	}
+	router.EnableCoverageTracking()
+
+	r, _ := http.NewRequest("GET", "/__stage__/users/5", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	expected := []string{"DELETE /users/:id", "GET /users"}
+	if report := router.CoverageReport(); !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected the routes never hit %v, saw %v", expected, report)
+	}
+
+	// HEAD requests served by a GET handler cover the GET route.
+	r, _ = http.NewRequest("HEAD", "/__stage__/users", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	expected = []string{"DELETE /users/:id"}
+	if report := router.CoverageReport(); !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected the routes never hit %v, saw %v", expected, report)
+	}
+}
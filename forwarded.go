@@ -0,0 +1,82 @@
+package lambdarouter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ForwardedFormat selects how ServeHTTP records the address of the client in the
+// request headers.
+type ForwardedFormat int
+
+const (
+	// XForwardedFor appends the address to a comma separated list, as in
+	// X-Forwarded-For: 203.0.113.1,198.51.100.2
+	XForwardedFor ForwardedFormat = iota
+	// RFC7239 appends a for= element, as in Forwarded: for=203.0.113.1, for="[2001:db8::1]"
+	RFC7239
+)
+
+// forwardedHeader returns the name of the header ServeHTTP adds the address of the
+// client to.
+func (t *TreeMux) forwardedHeader() string {
+	switch {
+	case t.ForwardedHeader != "":
+		return t.ForwardedHeader
+	case t.ForwardedFormat == RFC7239:
+		return "Forwarded"
+	default:
+		return "X-Forwarded-For"
+	}
+}
+
+// GetForwarded returns the value of the forwarded header configured on the router
+// for r, with the address of the client appended.
+func (t *TreeMux) GetForwarded(r *http.Request) string {
+	return getForwarded(r, t.forwardedHeader(), t.ForwardedFormat)
+}
+
+func getForwarded(r *http.Request, header string, format ForwardedFormat) string {
+	remoteIP := remoteAddr(r)
+	previous := r.Header.Get(header)
+	if format != RFC7239 {
+		return strings.Trim(previous+","+remoteIP, " ,")
+	}
+
+	node := remoteIP
+	if node == "" {
+		node = "unknown"
+	} else if strings.ContainsRune(node, ':') {
+		// IPv6 addresses are bracketed and must be quoted.
+		node = `"[` + node + `]"`
+	}
+	if previous == "" {
+		return "for=" + node
+	}
+	return previous + ", for=" + node
+}
+
+// ForwardedFor returns the client addresses listed by the for= parameters of an
+// RFC 7239 Forwarded header value, from the original client to the last proxy.
+// IPv6 addresses are returned without their brackets, and ports are dropped.
+func ForwardedFor(value string) []string {
+	var addrs []string
+	for _, element := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			pair = strings.TrimSpace(pair)
+			if len(pair) < 4 || !strings.EqualFold(pair[:4], "for=") {
+				continue
+			}
+			node := strings.Trim(pair[4:], `"`)
+			if strings.HasPrefix(node, "[") {
+				if end := strings.IndexByte(node, ']'); end >= 0 {
+					node = node[1:end]
+				}
+			} else if i := strings.IndexByte(node, ':'); i >= 0 {
+				node = node[:i]
+			}
+			addrs = append(addrs, node)
+		}
+	}
+	return addrs
+}
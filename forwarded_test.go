@@ -0,0 +1,65 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestForwardedHeader(t *testing.T) {
+	var headers map[string]string
+	router := New()
+	router.GET("/user", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		headers = req.Headers
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	serve := func(header, value, remote string) {
+		r, _ := http.NewRequest("GET", "/__stage__/user", nil)
+		r.RemoteAddr = remote
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve("", "", "192.0.2.1:1234")
+	if got := headers["X-Forwarded-For"]; got != "192.0.2.1" {
+		t.Errorf("Expected X-Forwarded-For 192.0.2.1, saw %q", got)
+	}
+	serve("X-Forwarded-For", "203.0.113.7", "192.0.2.1:1234")
+	if got := headers["X-Forwarded-For"]; got != "203.0.113.7,192.0.2.1" {
+		t.Errorf("Expected X-Forwarded-For 203.0.113.7,192.0.2.1, saw %q", got)
+	}
+
+	router.ForwardedFormat = RFC7239
+	serve("", "", "[2001:db8::1]:1234")
+	if got := headers["Forwarded"]; got != `for="[2001:db8::1]"` {
+		t.Errorf(`Expected Forwarded for="[2001:db8::1]", saw %q`, got)
+	}
+	serve("Forwarded", "for=203.0.113.7;proto=https", "192.0.2.1:1234")
+	if got := headers["Forwarded"]; got != "for=203.0.113.7;proto=https, for=192.0.2.1" {
+		t.Errorf("Expected Forwarded for=203.0.113.7;proto=https, for=192.0.2.1, saw %q", got)
+	}
+
+	router.ForwardedHeader = "X-Client-Chain"
+	serve("", "", "192.0.2.1:1234")
+	if got := headers["X-Client-Chain"]; got != "for=192.0.2.1" {
+		t.Errorf("Expected X-Client-Chain for=192.0.2.1, saw %q", got)
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	value := `for=203.0.113.7;proto=https, For="[2001:db8::1]:4711", for=192.0.2.1:80;by=proxy, proto=http`
+	expected := []string{"203.0.113.7", "2001:db8::1", "192.0.2.1"}
+	if got := ForwardedFor(value); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected addresses %v, saw %v", expected, got)
+	}
+	if got := ForwardedFor(""); got != nil {
+		t.Errorf("Expected no addresses for an empty header, saw %v", got)
+	}
+}
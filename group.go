@@ -7,8 +7,15 @@ import (
 )
 
 type Group struct {
-	path string
-	mux  *TreeMux
+	path   string
+	mux    *TreeMux
+	parent *Group
+	meta   map[string]interface{}
+
+	rewrite    string
+	hasRewrite bool
+
+	middleware []func(HandlerFunc) HandlerFunc
 }
 
 // Add a sub-group to this group
@@ -17,22 +24,129 @@ func (g *Group) NewGroup(path string) *Group {
 		panic("Group path must not be empty")
 	}
 
-	checkPath(path)
-	path = g.path + path
+	path = g.path + normalizePath(path)
 	//Don't want trailing slash as all sub-paths start with slash
 	if path[len(path)-1] == '/' {
 		path = path[:len(path)-1]
 	}
-	return &Group{path, g.mux}
+	return &Group{path: path, mux: g.mux, parent: g}
+}
+
+// SetMeta attaches a metadata value to the group, such as a tenant or an API
+// version. Sub-groups inherit the metadata of their parents, and handlers can read
+// it through MatchedGroup.
+func (g *Group) SetMeta(key string, value interface{}) *Group {
+	if g.meta == nil {
+		g.meta = make(map[string]interface{})
+	}
+	g.meta[key] = value
+	return g
+}
+
+// Meta returns the metadata value for key set on the group or the closest of its
+// parents, or nil when it was never set.
+func (g *Group) Meta(key string) interface{} {
+	for ; g != nil; g = g.parent {
+		if v, ok := g.meta[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// Use adds middleware to the group. The handlers registered afterwards on the group
+// and its sub-groups are wrapped by the middleware of their parents first, and then
+// by their own, so that the middleware runs outermost-first in the order it was
+// added:
+//
+//	api := router.NewGroup("/api").Use(logging)
+//	api.NewGroup("/admin").Use(requireAdmin).GET("/users", listUsers)
+//
+// A request to /api/admin/users goes through logging, then requireAdmin, then
+// reaches listUsers.
+func (g *Group) Use(mw ...func(HandlerFunc) HandlerFunc) *Group {
+	g.middleware = append(g.middleware, mw...)
+	return g
+}
+
+// wrap applies the middleware of g and its parents to handler.
+func (g *Group) wrap(handler HandlerFunc) HandlerFunc {
+	for ; g != nil; g = g.parent {
+		for i := len(g.middleware) - 1; i >= 0; i-- {
+			handler = g.middleware[i](handler)
+		}
+	}
+	return handler
+}
+
+// RewriteTo makes handlers of the group see request paths with the group prefix
+// replaced by prefix, which is useful to proxy to a backend laid out differently:
+//
+//	router.NewGroup("/v2").RewriteTo("/internal")
+//
+// A request to /v2/users then reaches its handler with a Path of /internal/users.
+// Routing is not affected, and sub-groups inherit the rewrite.
+func (g *Group) RewriteTo(prefix string) *Group {
+	checkPath(prefix)
+	if strings.HasSuffix(prefix, "/") {
+		prefix = prefix[:len(prefix)-1]
+	}
+	g.rewrite = prefix
+	g.hasRewrite = true
+	return g
+}
+
+// rewritePath applies the rewrite of g or of the closest of its parents to path.
+func (g *Group) rewritePath(path string) string {
+	for ; g != nil && !g.hasRewrite; g = g.parent {
+	}
+	if g == nil {
+		return path
+	}
+
+	// The path matched the pattern of the group, so its prefix has as many segments,
+	// wildcards included. The local stage segment is kept.
+	stage, prefix := segmentIndex(path, strings.Count(g.mux.path, "/")), segmentIndex(path, strings.Count(g.path, "/"))
+	if prefix < 0 {
+		return path
+	}
+	return path[:stage] + g.rewrite + path[prefix:]
+}
+
+// segmentIndex returns the index where the n first segments of path end, or -1 when
+// path has fewer segments.
+func segmentIndex(path string, n int) int {
+	index := 0
+	for ; n > 0; n-- {
+		next := strings.IndexByte(path[index+1:], '/')
+		if next < 0 {
+			if index+1 < len(path) && n == 1 {
+				return len(path)
+			}
+			return -1
+		}
+		index += next + 1
+	}
+	return index
 }
 
 // Path elements starting with : indicate a wildcard in the path. A wildcard will only match on a
 // single path segment. That is, the pattern `/post/:postid` will match on `/post/1` or `/post/1/`,
 // but not `/post/1/2`.
 //
+// A wildcard can be constrained by a regular expression the whole segment must match, as in
+// `/users/:id(\d+)`. `/users/abc` then falls through to the other routes, or gets a 404. The
+// expression is compiled when the route is added, and an invalid one panics.
+//
+// Paths are normalized before they are added: a missing leading slash is added and repeated
+// slashes are collapsed, so `users/:id` and `//users//:id` both register `/users/:id`. A
+// trailing slash is kept, see Trailing Slashes below.
+//
 // A path element starting with * is a catch-all, whose value will be a string containing all text
 // in the URL matched by the wildcards. For example, with a pattern of `/images/*path` and a
-// requested URL `images/abc/def`, path would contain `abc/def`.
+// requested URL `images/abc/def`, path would contain `abc/def`. The value is empty for
+// `images/`, and keeps the trailing slash of `images/abc/` unless RemoveCatchAllTrailingSlash
+// redirects it away.
 //
 // # Routing Rule Priority
 //
@@ -40,7 +154,7 @@ func (g *Group) NewGroup(path string) *Group {
 //
 // 1. Static path segments take the highest priority. If a segment and its subtree are able to match the URL, that match is returned.
 //
-// 2. Wildcards take second priority. For a particular wildcard to match, that wildcard and its subtree must match the URL.
+// 2. Wildcards take second priority. For a particular wildcard to match, that wildcard and its subtree must match the URL. Wildcards constrained by a regular expression are tried first, in the order they were added.
 //
 // 3. Finally, a catch-all rule will match when the earlier path segments have matched, and none of the static or wildcard conditions have matched. Catch-all rules must be at the end of a pattern.
 //
@@ -88,26 +202,38 @@ func (g *Group) NewGroup(path string) *Group {
 // 	GET /posts will redirect to /posts/.
 // 	GET /posts/ will match normally.
 // 	POST /posts will redirect to /posts/, because the GET method used a trailing slash.
-func (g *Group) Handle(method string, path string, handler HandlerFunc) {
+func (g *Group) Handle(method string, path string, handler HandlerFunc) *Route {
 
 	g.mux.mutex.Lock()
 	defer g.mux.mutex.Unlock()
 
+	route := &Route{method: method, path: g.mux.publicPath(g.path + normalizePath(path)), group: g, handler: handler, wrapped: handler}
+	handler = g.wrap(route.serve)
+	if max := g.mux.MaxParams; max > 0 && countParams(route.path) > max {
+		panic(fmt.Sprintf("Path %s has %d parameters, more than the maximum of %d",
+			route.path, countParams(route.path), max))
+	}
+	if name := duplicateParam(route.path); name != "" {
+		panic(fmt.Sprintf("Path %s has more than one parameter named %s", route.path, name))
+	}
 	addSlash := false
 	addOne := func(thePath string) {
-		node := g.mux.root.addPath(thePath[1:], nil, false)
+		node := g.mux.routeRoot().addPath(thePath[1:], nil, false)
 		if addSlash {
 			node.addSlash = true
 		}
+		node.pattern = route.path
 		node.setHandler(method, handler, false)
+		node.setRoute(method, route)
+		route.nodes = append(route.nodes, node)
 
 		if g.mux.HeadCanUseGet && method == "GET" && node.leafHandler["HEAD"] == nil {
 			node.setHandler("HEAD", handler, true)
+			node.setRoute("HEAD", route)
 		}
 	}
 
-	checkPath(path)
-	path = g.path + path
+	path = g.path + normalizePath(path)
 	if len(path) == 0 {
 		panic("Cannot map an empty path")
 	}
@@ -130,41 +256,42 @@ func (g *Group) Handle(method string, path string, handler HandlerFunc) {
 	}
 
 	addOne(path)
+	return route
 }
 
 // Syntactic sugar for Handle("GET", path, handler)
-func (g *Group) GET(path string, handler HandlerFunc) {
-	g.Handle("GET", path, handler)
+func (g *Group) GET(path string, handler HandlerFunc) *Route {
+	return g.Handle("GET", path, handler)
 }
 
 // Syntactic sugar for Handle("POST", path, handler)
-func (g *Group) POST(path string, handler HandlerFunc) {
-	g.Handle("POST", path, handler)
+func (g *Group) POST(path string, handler HandlerFunc) *Route {
+	return g.Handle("POST", path, handler)
 }
 
 // Syntactic sugar for Handle("PUT", path, handler)
-func (g *Group) PUT(path string, handler HandlerFunc) {
-	g.Handle("PUT", path, handler)
+func (g *Group) PUT(path string, handler HandlerFunc) *Route {
+	return g.Handle("PUT", path, handler)
 }
 
 // Syntactic sugar for Handle("DELETE", path, handler)
-func (g *Group) DELETE(path string, handler HandlerFunc) {
-	g.Handle("DELETE", path, handler)
+func (g *Group) DELETE(path string, handler HandlerFunc) *Route {
+	return g.Handle("DELETE", path, handler)
 }
 
 // Syntactic sugar for Handle("PATCH", path, handler)
-func (g *Group) PATCH(path string, handler HandlerFunc) {
-	g.Handle("PATCH", path, handler)
+func (g *Group) PATCH(path string, handler HandlerFunc) *Route {
+	return g.Handle("PATCH", path, handler)
 }
 
 // Syntactic sugar for Handle("HEAD", path, handler)
-func (g *Group) HEAD(path string, handler HandlerFunc) {
-	g.Handle("HEAD", path, handler)
+func (g *Group) HEAD(path string, handler HandlerFunc) *Route {
+	return g.Handle("HEAD", path, handler)
 }
 
 // Syntactic sugar for Handle("OPTIONS", path, handler)
-func (g *Group) OPTIONS(path string, handler HandlerFunc) {
-	g.Handle("OPTIONS", path, handler)
+func (g *Group) OPTIONS(path string, handler HandlerFunc) *Route {
+	return g.Handle("OPTIONS", path, handler)
 }
 
 func checkPath(path string) {
@@ -174,6 +301,49 @@ func checkPath(path string) {
 	}
 }
 
+// normalizePath returns the canonical form of a route or group path, which starts with a
+// slash and has no repeated slashes. An empty path stays empty.
+func normalizePath(path string) string {
+	if path == "" {
+		return path
+	}
+	path = "/" + path
+	for strings.Contains(path, "//") {
+		path = strings.Replace(path, "//", "/", -1)
+	}
+	return path
+}
+
+// countParams returns the number of wildcards and catch-alls in path.
+func countParams(path string) int {
+	count := 0
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) > 0 && (segment[0] == ':' || segment[0] == '*') {
+			count++
+		}
+	}
+	return count
+}
+
+// duplicateParam returns the first parameter name used twice in path, if any.
+func duplicateParam(path string) string {
+	seen := map[string]bool{}
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		if segment[0] == ':' {
+			name, _ = splitWildcard(name)
+		}
+		if seen[name] {
+			return name
+		}
+		seen[name] = true
+	}
+	return ""
+}
+
 func unescapeSpecial(s string) string {
 	// Look for sequences of \*, *, and \: that were escaped, and undo some of that escaping.
 
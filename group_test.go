@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -67,34 +68,201 @@ func TestGroupMethods(t *testing.T) {
 	}
 }
 
-func TestInvalidHandle(t *testing.T) {
-	defer func() {
-		if err := recover(); err == nil {
-			t.Error("Bad handle path should have caused a panic")
+func TestPathNormalization(t *testing.T) {
+	var called string
+	handler := func(name string) HandlerFunc {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			called = name
+			return events.APIGatewayProxyResponse{StatusCode: 200}, nil
 		}
-	}()
-	New().NewGroup("/foo").GET("bar", nil)
+	}
+
+	router := newLambdaRouter()
+	router.GET("users/:id", handler("users"))
+	router.NewGroup("foo").NewGroup("bar").GET("baz", handler("baz"))
+	router.NewGroup("//api/").GET("//v1//items", handler("items"))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/5", "users"},
+		{"/foo/bar/baz", "baz"},
+		{"/api/v1/items", "items"},
+	}
+	for _, test := range tests {
+		called = ""
+		router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: test.path, Path: test.path})
+		if called != test.expected {
+			t.Errorf("%s expected the %s handler, saw %q", test.path, test.expected, called)
+		}
+	}
+
+	// Variants of the same pattern are duplicates.
+	for _, variant := range []string{"/users/:id", "/users/:id/", "//users//:id"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected %s to panic as a duplicate of users/:id", variant)
+				}
+			}()
+			router.GET(variant, simpleHandler)
+		}()
+	}
 }
 
-func TestInvalidSubPath(t *testing.T) {
+func TestMaxParams(t *testing.T) {
+	router := New()
+	router.MaxParams = 2
+	router.GET("/a/:b/:c", simpleHandler)
+
 	defer func() {
-		if err := recover(); err == nil {
-			t.Error("Bad sub-path should have caused a panic")
+		err := recover()
+		if err == nil {
+			t.Fatal("Path with too many parameters should have caused a panic")
+		}
+		expected := "Path /a/:b/:c/*d has 3 parameters, more than the maximum of 2"
+		if err != expected {
+			t.Errorf("Expected panic %q, saw %q", expected, err)
 		}
 	}()
-	New().NewGroup("/foo").NewGroup("bar")
+	router.GET("/a/:b/:c/*d", simpleHandler)
 }
 
-func TestInvalidPath(t *testing.T) {
-	defer func() {
-		if err := recover(); err == nil {
-			t.Error("Bad path should have caused a panic")
+func TestDuplicateParams(t *testing.T) {
+	router := New()
+	router.GET("/a/:x/b/:y", simpleHandler)
+	router.GET("/c/:id/d/*path", simpleHandler)
+
+	for _, path := range []string{"/a/:id/b/:id", "/e/:id/f/*id", `/g/:id([0-9]+)/h/:id`} {
+		func() {
+			defer func() {
+				expected := "Path " + path + " has more than one parameter named id"
+				if err := recover(); err != expected {
+					t.Errorf("Expected panic %q, saw %v", expected, err)
+				}
+			}()
+			router.GET(path, simpleHandler)
+		}()
+	}
+}
+
+func TestMatchedGroup(t *testing.T) {
+	var version, tenant interface{}
+	router := New()
+	api := router.NewGroup("/api").SetMeta("tenant", "acme")
+	v2 := api.NewGroup("/v2").SetMeta("version", 2)
+	v2.GET("/users", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		g := MatchedGroup(ctx)
+		version = g.Meta("version")
+		tenant = g.Meta("tenant")
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/__stage__/api/v2/users", nil)
+	router.ServeHTTP(w, r)
+
+	if version != 2 {
+		t.Errorf("Expected version 2 from the matched group, saw %v", version)
+	}
+	if tenant != "acme" {
+		t.Errorf("Expected tenant acme inherited from the parent group, saw %v", tenant)
+	}
+	if v := router.Meta("version"); v != nil {
+		t.Errorf("Expected no version on the root group, saw %v", v)
+	}
+	if g := MatchedGroup(context.Background()); g != nil {
+		t.Errorf("Expected no group outside of a request, saw %v", g)
+	}
+}
+
+func TestGroupRewriteTo(t *testing.T) {
+	var path string
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		path = req.Path
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+
+	router := newLambdaRouter()
+	v2 := router.NewGroup("/v2").RewriteTo("/internal")
+	v2.GET("/users", handler)
+	v2.NewGroup("/:tenant").GET("/orders", handler)
+	router.GET("/v2", handler)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/v2/users", "/internal/users"},
+		{"/v2/acme/orders", "/internal/acme/orders"},
+		{"/v2", "/v2"},
+	}
+	for _, test := range tests {
+		path = ""
+		router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod:     "GET",
+			Resource:       "/{proxy+}",
+			Path:           test.path,
+			PathParameters: map[string]string{"proxy": test.path[1:]},
+		})
+		if path != test.expected {
+			t.Errorf("Request to %s expected handler path %s, saw %s", test.path, test.expected, path)
 		}
-	}()
-	New().NewGroup("foo")
+	}
+
+	// The local stage stays in front of the rewritten path.
+	local := New()
+	local.NewGroup("/v2").RewriteTo("/internal/").GET("/users", handler)
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/__stage__/v2/users", nil)
+	local.ServeHTTP(w, r)
+	if path != "/__stage__/internal/users" {
+		t.Errorf("Local request expected handler path /__stage__/internal/users, saw %s", path)
+	}
+}
+
+func TestGroupUse(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(HandlerFunc) HandlerFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name)
+				return next(ctx, req)
+			}
+		}
+	}
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		calls = append(calls, "handler")
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+
+	router := newLambdaRouter()
+	api := router.NewGroup("/api").Use(middleware("first"), middleware("second"))
+	api.GET("/users", handler)
+	api.NewGroup("/admin").Use(middleware("admin")).GET("/users", handler)
+	router.NewGroup("/public").GET("/users", handler)
+	router.UsingContext().NewContextGroup("/ctx").Use(middleware("context")).GET("/users", handler)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/users", "first second handler"},
+		{"/api/admin/users", "first second admin handler"},
+		{"/public/users", "handler"},
+		{"/ctx/users", "context handler"},
+	}
+	for _, test := range tests {
+		calls = nil
+		router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: test.path, Path: test.path})
+		if strings.Join(calls, " ") != test.expected {
+			t.Errorf("Request to %s expected %q, saw %q", test.path, test.expected, strings.Join(calls, " "))
+		}
+	}
 }
 
-//Liberally borrowed from router_test
+// Liberally borrowed from router_test
 func testGroupMethods(t *testing.T, reqGen RequestCreator, headCanUseGet bool) {
 	var result string
 	makeHandler := func(method string) HandlerFunc {
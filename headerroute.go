@@ -0,0 +1,97 @@
+package lambdarouter
+
+import "github.com/aws/aws-lambda-go/events"
+
+// headerRoute is a route of a node which only serves the requests carrying the
+// headers it was constrained with. See Route.Header.
+type headerRoute struct {
+	route   *Route
+	handler HandlerFunc
+}
+
+// Header restricts this route to the requests whose header name has the given
+// value, compared without regard to the case of the name. Routes registered on the
+// same method and pattern are then told apart by their headers, in the order they
+// were registered, and the route without constraint serves the other requests:
+//
+//	router.GET("/users", listUsersV2).Header("X-Api-Version", "2")
+//	router.GET("/users", listUsers)
+//
+// Calling Header several times requires all the headers. As registering a second
+// route without constraint on a method and pattern panics, the constrained routes
+// must be registered before the one serving the other requests. Without such a
+// route, the requests matching none of the constraints are not found.
+//
+// Like adding routes, calling Header while serving requests requires
+// SafeAddRoutesWhileRunning.
+func (r *Route) Header(name, value string) *Route {
+	t := r.group.mux
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if r.headers == nil {
+		for _, n := range r.nodes {
+			n.constrainRoute(r)
+		}
+	}
+	r.headers = append(r.headers, [2]string{name, value})
+	return r
+}
+
+// matchHeaders reports whether req has the headers required by r.
+func (r *Route) matchHeaders(req events.APIGatewayProxyRequest) bool {
+	for _, header := range r.headers {
+		if value, _ := headerValue(req.Headers, header[0]); value != header[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// constrainRoute moves the handlers of route, which was just registered without
+// constraint, to the header routes of n. The method stays in leafHandler with a
+// nil handler, so that it is still allowed and found by search, and another route
+// can take it.
+func (n *node) constrainRoute(route *Route) {
+	for method, existing := range n.leafRoute {
+		if existing != route {
+			continue
+		}
+		if n.headerRoutes == nil {
+			n.headerRoutes = make(map[string][]headerRoute)
+		}
+		n.headerRoutes[method] = append(n.headerRoutes[method], headerRoute{route: route, handler: n.leafHandler[method]})
+		n.leafHandler[method] = nil
+		delete(n.leafRoute, method)
+	}
+}
+
+// handles reports whether n has a handler for method, including the routes
+// constrained by headers, which search must not pass over for a wildcard sibling.
+func (n *node) handles(method string) bool {
+	return n.leafHandler[method] != nil || len(n.headerRoutes[method]) != 0
+}
+
+// matchHeaderRoute returns the first header route of n for method which req
+// matches, passing over the routes for which skip returns true.
+func (n *node) matchHeaderRoute(method string, req events.APIGatewayProxyRequest, skip func(*Route) bool) (*Route, HandlerFunc) {
+	for _, hr := range n.headerRoutes[method] {
+		if !skip(hr.route) && hr.route.matchHeaders(req) {
+			return hr.route, hr.handler
+		}
+	}
+	return nil, nil
+}
+
+// eachRoute calls fn with the routes of n and their method, the ones constrained
+// by headers last.
+func (n *node) eachRoute(fn func(method string, route *Route)) {
+	for method, route := range n.leafRoute {
+		fn(method, route)
+	}
+	for method, routes := range n.headerRoutes {
+		for _, hr := range routes {
+			fn(method, hr.route)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRouteHeader(t *testing.T) {
+	respond := func(body string) HandlerFunc {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			return events.APIGatewayProxyResponse{StatusCode: 200, Body: body}, nil
+		}
+	}
+
+	router := New()
+	router.GET("/users", respond("v2")).Header("X-Api-Version", "2")
+	router.GET("/users", respond("v3")).Header("X-Api-Version", "3").Header("X-Beta", "true")
+	router.GET("/users", respond("v1"))
+	router.GET("/reports", respond("v2 reports")).Header("X-Api-Version", "2")
+
+	tests := []struct {
+		path    string
+		headers map[string]string
+		code    int
+		body    string
+	}{
+		{"/users", map[string]string{"X-Api-Version": "2"}, 200, "v2"},
+		{"/users", map[string]string{"x-api-version": "2"}, 200, "v2"},
+		{"/users", map[string]string{"X-Api-Version": "3", "X-Beta": "true"}, 200, "v3"},
+		{"/users", map[string]string{"X-Api-Version": "3"}, 200, "v1"},
+		{"/users", nil, 200, "v1"},
+		{"/reports", map[string]string{"X-Api-Version": "2"}, 200, "v2 reports"},
+		{"/reports", nil, http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/prod"+test.path, nil)
+		for name, value := range test.headers {
+			r.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, r)
+		if w.Code != test.code || (test.body != "" && w.Body.String() != test.body) {
+			t.Errorf("%s with %v expected %d %q, saw %d %q", test.path, test.headers, test.code, test.body, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/prod/reports", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for a method without route, saw %d", w.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a second route without constraint to panic")
+		}
+	}()
+	router.GET("/users", respond("again"))
+}
+
+func TestRouteHeaderWildcardSibling(t *testing.T) {
+	respond := func(body string) HandlerFunc {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			return events.APIGatewayProxyResponse{StatusCode: 200, Body: body}, nil
+		}
+	}
+
+	router := New()
+	router.GET("/users/admin", respond("admin v2")).Header("X-Api-Version", "2")
+	router.GET("/users/admin", respond("admin beta")).Header("X-Beta", "true").Tag("beta")
+	router.GET("/users/admin", respond("admin"))
+	router.GET("/users/:id", respond("by id"))
+	router.SetTagEnabled("beta", false)
+
+	tests := []struct {
+		headers map[string]string
+		body    string
+	}{
+		{map[string]string{"X-Api-Version": "2"}, "admin v2"},
+		{map[string]string{"X-Beta": "true"}, "admin"},
+		{nil, "admin"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/prod/users/admin", nil)
+		for name, value := range test.headers {
+			r.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, r)
+		if w.Code != 200 || w.Body.String() != test.body {
+			t.Errorf("/users/admin with %v expected 200 %q, saw %d %q", test.headers, test.body, w.Code, w.Body.String())
+		}
+	}
+
+	router = New()
+	router.GET("/users/admin", respond("admin v2")).Header("X-Api-Version", "2")
+	router.GET("/users/:id", respond("by id"))
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/prod/users/admin", nil)
+	r.Header.Set("X-Api-Version", "2")
+	router.ServeHTTP(w, r)
+	if w.Body.String() != "admin v2" {
+		t.Errorf("Expected the constrained static route to win over its wildcard sibling, saw %d %q", w.Code, w.Body.String())
+	}
+}
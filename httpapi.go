@@ -0,0 +1,117 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ServeLambdaV2 serves a request of an API Gateway HTTP API sent with the 2.0
+// payload format. The request is converted to the REST API shape used everywhere
+// else, so handlers and helpers work unchanged, and the response is converted back.
+// LambdaHandler calls it for the events of this format.
+func (t *TreeMux) ServeLambdaV2(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	ctx = context.WithValue(ctx, rawQueryContextKey, req.RawQueryString)
+	res, err := t.ServeLambda(ctx, fromV2Request(req))
+	return toV2Response(res), err
+}
+
+// fromV2Request converts a 2.0 payload to a REST API request. The path is the
+// resource, so that ServeLambda routes on the path as it was requested.
+func fromV2Request(req events.APIGatewayV2HTTPRequest) events.APIGatewayProxyRequest {
+	path := req.RequestContext.HTTP.Path
+	if path == "" {
+		path = req.RawPath
+	}
+	// Named stages are part of the path, unlike with REST APIs.
+	if stage := req.RequestContext.Stage; stage != "" && stage != "$default" {
+		if trimmed := strings.TrimPrefix(path, "/"+stage); trimmed != path && (trimmed == "" || trimmed[0] == '/') {
+			path = trimmed
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	headers := make(map[string]string, len(req.Headers)+1)
+	for key, value := range req.Headers {
+		headers[key] = value
+	}
+	if len(req.Cookies) > 0 {
+		headers["cookie"] = strings.Join(req.Cookies, "; ")
+	}
+
+	var multiQuery map[string][]string
+	if query, err := url.ParseQuery(req.RawQueryString); err == nil && len(query) > 0 {
+		multiQuery = query
+	}
+
+	event := events.APIGatewayProxyRequest{
+		Resource:                        path,
+		Path:                            path,
+		HTTPMethod:                      req.RequestContext.HTTP.Method,
+		Headers:                         headers,
+		QueryStringParameters:           req.QueryStringParameters,
+		MultiValueQueryStringParameters: multiQuery,
+		PathParameters:                  req.PathParameters,
+		StageVariables:                  req.StageVariables,
+		Body:                            req.Body,
+		IsBase64Encoded:                 req.IsBase64Encoded,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:        req.RequestContext.AccountID,
+			Stage:            req.RequestContext.Stage,
+			DomainName:       req.RequestContext.DomainName,
+			DomainPrefix:     req.RequestContext.DomainPrefix,
+			RequestID:        req.RequestContext.RequestID,
+			Protocol:         req.RequestContext.HTTP.Protocol,
+			ResourcePath:     path,
+			Path:             req.RawPath,
+			HTTPMethod:       req.RequestContext.HTTP.Method,
+			RequestTime:      req.RequestContext.Time,
+			RequestTimeEpoch: req.RequestContext.TimeEpoch,
+			APIID:            req.RequestContext.APIID,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  req.RequestContext.HTTP.SourceIP,
+				UserAgent: req.RequestContext.HTTP.UserAgent,
+			},
+		},
+	}
+	if auth := req.RequestContext.Authorizer; auth != nil && auth.Lambda != nil {
+		event.RequestContext.Authorizer = auth.Lambda
+	}
+	return event
+}
+
+// toV2Response converts a REST API response to the 2.0 payload format, which
+// carries the cookies to set in a field of their own.
+func toV2Response(res events.APIGatewayProxyResponse) events.APIGatewayV2HTTPResponse {
+	out := events.APIGatewayV2HTTPResponse{
+		StatusCode:      res.StatusCode,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+	}
+	for key, value := range res.Headers {
+		if http.CanonicalHeaderKey(key) == "Set-Cookie" {
+			out.Cookies = append(out.Cookies, value)
+			continue
+		}
+		if out.Headers == nil {
+			out.Headers = make(map[string]string, len(res.Headers))
+		}
+		out.Headers[key] = value
+	}
+	for key, values := range res.MultiValueHeaders {
+		if http.CanonicalHeaderKey(key) == "Set-Cookie" {
+			out.Cookies = append(out.Cookies, values...)
+			continue
+		}
+		if out.MultiValueHeaders == nil {
+			out.MultiValueHeaders = make(map[string][]string, len(res.MultiValueHeaders))
+		}
+		out.MultiValueHeaders[key] = values
+	}
+	return out
+}
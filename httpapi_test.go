@@ -0,0 +1,81 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func newV2Request(method, path string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{Version: "2.0", RouteKey: "$default", RawPath: path}
+	req.RequestContext.Stage = "$default"
+	req.RequestContext.HTTP.Method = method
+	req.RequestContext.HTTP.Path = path
+	return req
+}
+
+func TestServeLambdaV2(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/users/:id", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "text/plain", "Set-Cookie": "session=abc"},
+			Body:       req.PathParameters["id"] + " " + strings.Join(req.MultiValueQueryStringParameters["tag"], ",") + " " + req.Headers["cookie"],
+		}, nil
+	})
+
+	req := newV2Request("GET", "/users/42")
+	req.RawQueryString = "tag=a&tag=b"
+	req.Cookies = []string{"a=1", "b=2"}
+	res, err := router.ServeLambdaV2(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || res.Body != "42 a,b a=1; b=2" {
+		t.Errorf("Expected 200 with the request details, saw %d %q", res.StatusCode, res.Body)
+	}
+	if res.Headers["Content-Type"] != "text/plain" || len(res.Cookies) != 1 || res.Cookies[0] != "session=abc" {
+		t.Errorf("Expected the headers and cookies to be converted, saw %v and %v", res.Headers, res.Cookies)
+	}
+
+	// Named stages prefix the path.
+	req = newV2Request("GET", "/prod/users/7")
+	req.RequestContext.Stage = "prod"
+	res, _ = router.ServeLambdaV2(context.Background(), req)
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected the stage to be stripped from the path, saw %d", res.StatusCode)
+	}
+
+	res, _ = router.ServeLambdaV2(context.Background(), newV2Request("POST", "/users/42"))
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for an unknown method, saw %d", res.StatusCode)
+	}
+}
+
+func TestLambdaHandlerV2(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/abc", simpleHandler)
+
+	raw := mustMarshal(t, newV2Request("GET", "/abc"))
+	if eventType := GetEventType(raw); eventType != HTTPV2 {
+		t.Fatalf("Expected HTTPV2, saw %s", eventType)
+	}
+
+	res, err := router.LambdaHandler()(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, ok := res.(events.APIGatewayV2HTTPResponse)
+	if !ok || v2.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected a 2.0 response with status 204, saw %#v", res)
+	}
+
+	// The payload must survive the round trip through JSON.
+	if _, err := json.Marshal(res); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,198 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// EventType identifies the kind of payload a Lambda invocation received.
+type EventType int
+
+const (
+	Unknown    EventType = iota // Not a payload the router knows how to serve
+	HTTP                        // API Gateway REST proxy request
+	HTTPV2                      // API Gateway HTTP API request, payload format 2.0
+	Websocket                   // API Gateway WebSocket request
+	Authorizer                  // API Gateway REQUEST or TOKEN authorizer request
+	ALB                         // Application Load Balancer target group request
+)
+
+func (e EventType) String() string {
+	switch e {
+	case HTTP:
+		return "HTTP"
+	case HTTPV2:
+		return "HTTPV2"
+	case Websocket:
+		return "Websocket"
+	case Authorizer:
+		return "Authorizer"
+	case ALB:
+		return "ALB"
+	default:
+		return "Unknown"
+	}
+}
+
+// eventProbe holds the fields used to tell the payloads apart.
+type eventProbe struct {
+	Type           string `json:"type"`
+	Version        string `json:"version"`
+	MethodArn      string `json:"methodArn"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		ConnectionID string `json:"connectionId"`
+		HTTP         struct {
+			Method string `json:"method"`
+		} `json:"http"`
+		ELB struct {
+			TargetGroupArn string `json:"targetGroupArn"`
+		} `json:"elb"`
+	} `json:"requestContext"`
+}
+
+// GetEventType inspects a raw Lambda payload and reports which kind of event it is.
+func GetEventType(raw json.RawMessage) EventType {
+	var probe eventProbe
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return Unknown
+	}
+
+	switch {
+	case probe.MethodArn != "" && (probe.Type == "REQUEST" || probe.Type == "TOKEN"):
+		// Authorizer requests also carry an httpMethod, so they are checked first.
+		return Authorizer
+	case probe.RequestContext.ConnectionID != "":
+		return Websocket
+	case probe.RequestContext.ELB.TargetGroupArn != "":
+		// ALB requests also carry an httpMethod, so they are checked before HTTP.
+		return ALB
+	case probe.HTTPMethod != "":
+		return HTTP
+	case probe.Version == "2.0" && probe.RequestContext.HTTP.Method != "":
+		return HTTPV2
+	default:
+		return Unknown
+	}
+}
+
+// ErrUnsupportedEvent is returned by the handler of LambdaHandler for payloads it
+// can not dispatch.
+var ErrUnsupportedEvent = errors.New("lambdarouter: unsupported event")
+
+// LambdaPanicHandler turns a panic recovered while serving an event of the given
+// type into the response of the invocation.
+type LambdaPanicHandler func(ctx context.Context, eventType EventType, err interface{}) (interface{}, error)
+
+// SimpleLambdaPanicHandler logs the panic and answers with a response shaped for the
+// event type: a 500 for HTTP, ALB and WebSocket requests, and a policy denying access for
+// authorizer requests.
+func SimpleLambdaPanicHandler(ctx context.Context, eventType EventType, err interface{}) (interface{}, error) {
+	fmt.Printf("panic serving %s event: %v\n", eventType, err)
+	switch eventType {
+	case Authorizer:
+		return events.APIGatewayCustomAuthorizerResponse{
+			PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+				Version: "2012-10-17",
+				Statement: []events.IAMPolicyStatement{{
+					Action:   []string{"execute-api:Invoke"},
+					Effect:   "Deny",
+					Resource: []string{"*"},
+				}},
+			},
+		}, nil
+	case HTTPV2:
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: 500,
+			Body:       `{"error": "Internal Server Error"}`,
+		}, nil
+	case ALB:
+		return events.ALBTargetGroupResponse{
+			StatusCode:        500,
+			StatusDescription: "500 Internal Server Error",
+			Body:              `{"error": "Internal Server Error"}`,
+		}, nil
+	default:
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       `{"error": "Internal Server Error"}`,
+		}, nil
+	}
+}
+
+// LambdaHandler returns a handler suitable for lambda.Start which serves every
+// event type from a single function. HTTP requests are routed through the tree as
+// with ServeLambda, ServeLambdaV2 for HTTP APIs using the 2.0 payload format, or
+// ServeALB for Application Load Balancer requests. WebSocket requests are
+// dispatched to TreeMux.Websocket and authorizer requests are passed to the
+// function given to SetAuthorizer. A panic in any of them is passed to
+// TreeMux.LambdaPanicHandler when it is set, unless the panic of an HTTP
+// handler was already recovered by TreeMux.ServeLambdaPanicHandler. Warming pings
+// are answered without being routed, see SetWarmerDetector.
+//
+//	lambda.Start(router.LambdaHandler())
+func (t *TreeMux) LambdaHandler() func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, raw json.RawMessage) (res interface{}, err error) {
+		eventType := GetEventType(raw)
+		if t.LambdaPanicHandler != nil {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					res, err = t.LambdaPanicHandler(ctx, eventType, recovered)
+				}
+			}()
+		}
+
+		if t.warmerDetector != nil && t.warmerDetector(raw) {
+			return t.serveWarmer(ctx)
+		}
+
+		switch eventType {
+		case HTTP:
+			var req events.APIGatewayProxyRequest
+			if err := json.Unmarshal(raw, &req); err != nil {
+				return nil, err
+			}
+			return t.ServeLambda(ctx, req)
+
+		case HTTPV2:
+			var req events.APIGatewayV2HTTPRequest
+			if err := json.Unmarshal(raw, &req); err != nil {
+				return nil, err
+			}
+			return t.ServeLambdaV2(ctx, req)
+
+		case ALB:
+			var req events.ALBTargetGroupRequest
+			if err := json.Unmarshal(raw, &req); err != nil {
+				return nil, err
+			}
+			return t.ServeALB(ctx, req)
+
+		case Websocket:
+			if t.Websocket == nil {
+				break
+			}
+			var req events.APIGatewayWebsocketProxyRequest
+			if err := json.Unmarshal(raw, &req); err != nil {
+				return nil, err
+			}
+			return t.ServeWebsocket(ctx, req)
+
+		case Authorizer:
+			if t.authorizer == nil {
+				break
+			}
+			var req events.APIGatewayCustomAuthorizerRequestTypeRequest
+			if err := json.Unmarshal(raw, &req); err != nil {
+				return nil, err
+			}
+			return t.authorizer(ctx, req)
+		}
+
+		return nil, ErrUnsupportedEvent
+	}
+}
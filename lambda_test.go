@@ -0,0 +1,170 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestGetEventType(t *testing.T) {
+	httpEvent := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/abc"}
+	wsEvent := events.APIGatewayWebsocketProxyRequest{}
+	wsEvent.RequestContext.ConnectionID = "abc="
+	wsEvent.RequestContext.RouteKey = "$connect"
+	authEvent := events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		Type:       "REQUEST",
+		MethodArn:  "arn:aws:execute-api:eu-west-1:123:api/prod/GET/abc",
+		HTTPMethod: "GET",
+	}
+	albEvent := events.ALBTargetGroupRequest{HTTPMethod: "GET", Path: "/abc"}
+	albEvent.RequestContext.ELB.TargetGroupArn = "arn:aws:elasticloadbalancing:eu-west-1:123:targetgroup/api/abc"
+
+	tests := []struct {
+		raw      json.RawMessage
+		expected EventType
+	}{
+		{mustMarshal(t, httpEvent), HTTP},
+		{mustMarshal(t, wsEvent), Websocket},
+		{mustMarshal(t, authEvent), Authorizer},
+		{mustMarshal(t, albEvent), ALB},
+		{json.RawMessage(`{"source": "aws.events"}`), Unknown},
+		{json.RawMessage(`not json`), Unknown},
+	}
+
+	for _, tc := range tests {
+		if eventType := GetEventType(tc.raw); eventType != tc.expected {
+			t.Errorf("Expected %s for %s, saw %s", tc.expected, tc.raw, eventType)
+		}
+	}
+}
+
+func TestLambdaHandler(t *testing.T) {
+	var called string
+	router := New()
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = "http"
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+
+	handler := router.LambdaHandler()
+
+	// Nothing is set up for WebSocket and authorizer events yet.
+	wsEvent := events.APIGatewayWebsocketProxyRequest{}
+	wsEvent.RequestContext.ConnectionID = "abc="
+	wsEvent.RequestContext.RouteKey = "$connect"
+	if _, err := handler(context.Background(), mustMarshal(t, wsEvent)); err != ErrUnsupportedEvent {
+		t.Errorf("Expected ErrUnsupportedEvent without a WebsocketMux, saw %v", err)
+	}
+
+	router.Websocket = NewWebsocket()
+	router.Websocket.On("$connect", func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = "websocket"
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		called = "authorizer"
+		return events.APIGatewayCustomAuthorizerResponse{PrincipalID: "user"}, nil
+	})
+
+	httpEvent := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/__stage__/abc", Resource: "/__stage__/abc"}
+	authEvent := events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		Type:       "REQUEST",
+		MethodArn:  "arn:aws:execute-api:eu-west-1:123:api/prod/GET/abc",
+		HTTPMethod: "GET",
+	}
+
+	tests := []struct {
+		raw      json.RawMessage
+		expected string
+	}{
+		{mustMarshal(t, httpEvent), "http"},
+		{mustMarshal(t, wsEvent), "websocket"},
+		{mustMarshal(t, authEvent), "authorizer"},
+	}
+
+	for _, tc := range tests {
+		called = ""
+		res, err := handler(context.Background(), tc.raw)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.expected, err)
+		}
+		if called != tc.expected {
+			t.Errorf("Expected the %s handler to be called, saw %q", tc.expected, called)
+		}
+		if _, err := json.Marshal(res); err != nil {
+			t.Errorf("Response for %s can not be marshaled: %v", tc.expected, err)
+		}
+	}
+
+	if _, err := handler(context.Background(), json.RawMessage(`{}`)); err != ErrUnsupportedEvent {
+		t.Errorf("Expected ErrUnsupportedEvent for an unknown event, saw %v", err)
+	}
+}
+
+func TestLambdaHandlerPanic(t *testing.T) {
+	router := New()
+	router.GET("/abc", panicHandler)
+	router.Websocket = NewWebsocket()
+	router.Websocket.On("$connect", func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		panic("websocket")
+	})
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		panic("authorizer")
+	})
+	handler := router.LambdaHandler()
+
+	httpEvent := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/__stage__/abc", Resource: "/__stage__/abc"}
+	res, err := handler(context.Background(), mustMarshal(t, httpEvent))
+	if r, ok := res.(events.APIGatewayProxyResponse); err != nil || !ok || r.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 response for a panicking HTTP handler, saw %#v, %v", res, err)
+	}
+
+	wsEvent := events.APIGatewayWebsocketProxyRequest{}
+	wsEvent.RequestContext.ConnectionID = "abc="
+	wsEvent.RequestContext.RouteKey = "$connect"
+	res, err = handler(context.Background(), mustMarshal(t, wsEvent))
+	if r, ok := res.(events.APIGatewayProxyResponse); err != nil || !ok || r.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 response for a panicking WebSocket handler, saw %#v, %v", res, err)
+	}
+
+	authEvent := events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		Type:      "REQUEST",
+		MethodArn: "arn:aws:execute-api:eu-west-1:123:api/prod/GET/abc",
+	}
+	res, err = handler(context.Background(), mustMarshal(t, authEvent))
+	r, ok := res.(events.APIGatewayCustomAuthorizerResponse)
+	if err != nil || !ok || len(r.PolicyDocument.Statement) != 1 || r.PolicyDocument.Statement[0].Effect != "Deny" {
+		t.Errorf("Expected a deny policy for a panicking authorizer, saw %#v, %v", res, err)
+	}
+
+	// A custom handler sees the event type.
+	var eventType EventType
+	router.LambdaPanicHandler = func(ctx context.Context, e EventType, err interface{}) (interface{}, error) {
+		eventType = e
+		return nil, fmt.Errorf("%v", err)
+	}
+	if _, err := handler(context.Background(), mustMarshal(t, wsEvent)); err == nil || err.Error() != "websocket" || eventType != Websocket {
+		t.Errorf("Expected the custom handler to see the Websocket panic, saw %v for %s", err, eventType)
+	}
+
+	router.LambdaPanicHandler = nil
+	router.ServeLambdaPanicHandler = nil
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected the panic to go through without a LambdaPanicHandler")
+		}
+	}()
+	handler(context.Background(), mustMarshal(t, httpEvent))
+}
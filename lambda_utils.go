@@ -4,18 +4,55 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"io/ioutil"
+	"log"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
+// headerValue looks up name in headers without regard to case, since API Gateway
+// passes header names through as the client sent them.
+func headerValue(headers map[string]string, name string) (string, bool) {
+	if v, ok := headers[name]; ok {
+		return v, true
+	}
+	for key, v := range headers {
+		if strings.EqualFold(key, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Header returns the value of the header name of req without regard to case, so
+// that handlers read "content-type" and "Content-Type" alike whether the request
+// comes from API Gateway, which keeps the casing of the client, or from ServeHTTP,
+// which canonicalizes it. It returns an empty string when the header is missing.
+func Header(req events.APIGatewayProxyRequest, name string) string {
+	if v, ok := headerValue(req.Headers, name); ok {
+		return v
+	}
+	for key, values := range req.MultiValueHeaders {
+		if strings.EqualFold(key, name) && len(values) != 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
 func LambdaGenerateRawQuery(request events.APIGatewayProxyRequest) string {
 	tmp := url.Values{}
 	for i := range request.QueryStringParameters {
@@ -24,6 +61,20 @@ func LambdaGenerateRawQuery(request events.APIGatewayProxyRequest) string {
 	return tmp.Encode()
 }
 
+// RawQueryString returns the query string of req. When serving locally, it is the
+// query exactly as the client sent it. API Gateway only passes the parsed
+// parameters on, so on Lambda the query is rebuilt from them, including repeated
+// keys, with the keys in sorted order and a canonical encoding.
+func RawQueryString(ctx context.Context, req events.APIGatewayProxyRequest) string {
+	if raw, ok := ctx.Value(rawQueryContextKey).(string); ok {
+		return raw
+	}
+	if len(req.MultiValueQueryStringParameters) != 0 {
+		return url.Values(req.MultiValueQueryStringParameters).Encode()
+	}
+	return LambdaGenerateRawQuery(req)
+}
+
 func LambdaRedirect(ctx context.Context, req events.APIGatewayProxyRequest, newUrl string, code int) (events.APIGatewayProxyResponse, error) {
 	return events.APIGatewayProxyResponse{
 		StatusCode: code,
@@ -52,6 +103,13 @@ func LambdaNotAllowed(ctx context.Context, req events.APIGatewayProxyRequest, al
 	}, nil
 }
 
+func LambdaForbidden(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: 403,
+		Body:       `{"error": "Forbidden"}`,
+	}, nil
+}
+
 func LambdaNotFound(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	return events.APIGatewayProxyResponse{
 		StatusCode: 404,
@@ -59,62 +117,405 @@ func LambdaNotFound(ctx context.Context, req events.APIGatewayProxyRequest) (eve
 	}, nil
 }
 
+// LambdaBadRequest answers with 400 Bad Request, reporting err to the client, for
+// example the one returned by BindStrict.
+func LambdaBadRequest(ctx context.Context, req events.APIGatewayProxyRequest, err error) (events.APIGatewayProxyResponse, error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return events.APIGatewayProxyResponse{
+		StatusCode: 400,
+		Body:       string(body),
+	}, nil
+}
+
+func LambdaRequestTooLarge(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: 413,
+		Body:       `{"error": "Request Entity Too Large"}`,
+	}, nil
+}
+
+func LambdaTooManyRequests(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: 429,
+		Body:       `{"error": "Too Many Requests"}`,
+	}, nil
+}
+
+func LambdaServiceUnavailable(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: 503,
+		Body:       `{"error": "Service Unavailable"}`,
+	}, nil
+}
+
+func LambdaGatewayTimeout(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: 504,
+		Body:       `{"error": "Gateway Timeout"}`,
+	}, nil
+}
+
 func GetForwarded(r *http.Request) string {
+	return getForwarded(r, "X-Forwarded-For", XForwardedFor)
+}
+
+func remoteAddr(r *http.Request) string {
 	var remoteIP string
 	if strings.ContainsRune(r.RemoteAddr, ':') {
 		remoteIP, _, _ = net.SplitHostPort(r.RemoteAddr)
 	} else {
 		remoteIP = r.RemoteAddr
 	}
-	return strings.Trim(fmt.Sprintf("%s,%s", r.Header.Get("X-Forwarded-For"), remoteIP), " ,")
+	return remoteIP
+}
+
+// LocalAPIID is the API id set in the request context of the requests converted by
+// RequestToLambda and the local server, where API Gateway would set the id of the
+// REST API.
+var LocalAPIID = "localhost"
+
+// APIID returns the id of the API Gateway REST API req was sent to, or LocalAPIID
+// for requests served locally.
+func APIID(req events.APIGatewayProxyRequest) string {
+	return req.RequestContext.APIID
+}
+
+// ResourcePath returns the resource req was routed to, such as /users/{id}. Behind
+// a {proxy+} resource, ServeLambda sets it to the pattern of the matched route, as
+// does the local server, instead of the proxy resource.
+func ResourcePath(req events.APIGatewayProxyRequest) string {
+	if req.RequestContext.ResourcePath != "" {
+		return req.RequestContext.ResourcePath
+	}
+	return req.Resource
 }
 
+// localResourceID returns a stable id for resource, in the form of the ids API
+// Gateway gives to the resources of a REST API.
+func localResourceID(resource string) string {
+	h := fnv.New32a()
+	h.Write([]byte(resource))
+	id := strconv.FormatUint(uint64(h.Sum32()%2176782336), 36)
+	return strings.Repeat("0", 6-len(id)) + id
+}
+
+// RequestToLambda converts req to the event API Gateway would send for it. Bodies
+// of a binary content type, see BinaryMediaTypes, are base64-encoded.
 func RequestToLambda(req *http.Request) (events.APIGatewayProxyRequest, error) {
+	e := newLambdaRequest(req, requestOptions{forwardedHeader: "X-Forwarded-For"})
+	if req.Body != nil {
+		e.Body = readBody(req.Body, 0)
+		encodeBody(&e)
+	}
+	return e, nil
+}
+
+// QueryParamMode selects which value of a repeated query parameter ends up in
+// QueryStringParameters. MultiValueQueryStringParameters always has all of them.
+type QueryParamMode int
+
+const (
+	QueryParamFirst QueryParamMode = iota // Keep the first value
+	QueryParamLast                        // Keep the last value
+	QueryParamAll                         // Join all the values with commas
+)
+
+// requestOptions control how an http.Request is converted for the handlers.
+type requestOptions struct {
+	forwardedHeader string
+	forwardedFormat ForwardedFormat
+	queryMode       QueryParamMode
+}
+
+// newLambdaRequest converts everything but the body of req.
+func newLambdaRequest(req *http.Request, opts requestOptions) events.APIGatewayProxyRequest {
 	e := events.APIGatewayProxyRequest{
-		HTTPMethod:            req.Method,
-		Path:                  strings.Split(req.URL.RequestURI(), "?")[0],
-		Resource:              strings.Split(req.URL.RequestURI(), "?")[0],
-		Headers:               map[string]string{},
-		QueryStringParameters: map[string]string{},
-		PathParameters:        map[string]string{},
-		StageVariables:        map[string]string{},
+		HTTPMethod:                      req.Method,
+		Path:                            strings.Split(req.URL.RequestURI(), "?")[0],
+		Resource:                        strings.Split(req.URL.RequestURI(), "?")[0],
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		PathParameters:                  map[string]string{},
+		StageVariables:                  map[string]string{},
 	}
 	// e.RequestContext.RequestID = utils.UUID()
-	// e.RequestContext.ResourcePath = params.Path
+	e.RequestContext.APIID = LocalAPIID
 	e.RequestContext.HTTPMethod = req.Method
-	for i := range req.URL.Query() {
-
-		e.QueryStringParameters[i] = req.URL.Query().Get(i)
+	e.RequestContext.Protocol = req.Proto
+	for key, values := range req.URL.Query() {
+		e.MultiValueQueryStringParameters[key] = values
+		switch opts.queryMode {
+		case QueryParamLast:
+			e.QueryStringParameters[key] = values[len(values)-1]
+		case QueryParamAll:
+			e.QueryStringParameters[key] = strings.Join(values, ",")
+		default:
+			e.QueryStringParameters[key] = values[0]
+		}
 	}
-	for i := range req.Header {
+	for i, values := range req.Header {
 		e.Headers[i] = req.Header.Get(i)
+		e.MultiValueHeaders[i] = values
 	}
-	e.Headers["X-Forwarded-For"] = GetForwarded(req)
-	if req.Body != nil {
-		b, _ := ioutil.ReadAll(req.Body)
-		e.Body = fmt.Sprintf("%s", b)
+	if _, ok := e.Headers["Host"]; !ok && req.Host != "" {
+		// The http package moves the Host header out of req.Header.
+		setHeader(&e, "Host", req.Host)
 	}
-	return e, nil
+	setHeader(&e, http.CanonicalHeaderKey(opts.forwardedHeader), getForwarded(req, opts.forwardedHeader, opts.forwardedFormat))
+	// Like API Gateway, the router sets the protocol itself, so that a client can not
+	// pass for a TLS one by sending the header.
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+	setHeader(&e, "X-Forwarded-Proto", proto)
+	return e
 }
 
-func ResToHttp(w http.ResponseWriter, req *http.Request, res events.APIGatewayProxyResponse) {
-	for key := range res.Headers {
-		w.Header().Set(key, res.Headers[key])
+// setHeader sets the single and multi-value forms of a request header.
+func setHeader(e *events.APIGatewayProxyRequest, key, value string) {
+	e.Headers[key] = value
+	e.MultiValueHeaders[key] = []string{value}
+}
+
+// IsTLS reports whether the client reached the API over HTTPS, according to the
+// X-Forwarded-Proto header set by API Gateway, or by the router when serving locally.
+func IsTLS(req events.APIGatewayProxyRequest) bool {
+	proto, _ := headerValue(req.Headers, "X-Forwarded-Proto")
+	// Proxies in a chain append their own value, the first one is the client's.
+	if i := strings.IndexByte(proto, ','); i >= 0 {
+		proto = proto[:i]
 	}
-	w.WriteHeader(res.StatusCode)
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// WrapHTTPHandler adapts a standard http.Handler into a HandlerFunc, so that
+// existing HTTP handlers can be mounted on the router. The path parameters are
+// available to h through ContextParams on the request context.
+func WrapHTTPHandler(h http.Handler) HandlerFunc {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		r, err := lambdaToRequest(ctx, req)
+		if err != nil {
+			return events.APIGatewayProxyResponse{}, err
+		}
+		w := &responseRecorder{header: http.Header{}}
+		h.ServeHTTP(w, r)
+		return w.response(), nil
+	}
+}
+
+// lambdaToRequest is the reverse of RequestToLambda.
+func lambdaToRequest(ctx context.Context, req events.APIGatewayProxyRequest) (*http.Request, error) {
+	body, err := RequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	u := url.URL{Path: req.Path, RawQuery: LambdaGenerateRawQuery(req)}
+	r, err := http.NewRequest(req.HTTPMethod, u.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range req.Headers {
+		r.Header.Set(key, value)
+	}
+	for key, values := range req.MultiValueHeaders {
+		r.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	r.Host = r.Header.Get("Host")
+	return r.WithContext(AddParamsToContext(ctx, req.PathParameters)), nil
+}
+
+// responseRecorder collects what an http.Handler writes into a Lambda response.
+type responseRecorder struct {
+	header      http.Header
+	code        int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *responseRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.code = code
+	w.wroteHeader = true
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *responseRecorder) response() events.APIGatewayProxyResponse {
+	w.WriteHeader(http.StatusOK)
+	res := events.APIGatewayProxyResponse{
+		StatusCode:        w.code,
+		Headers:           make(map[string]string, len(w.header)),
+		MultiValueHeaders: make(map[string][]string, len(w.header)),
+	}
+	// Headers such as Set-Cookie can not be joined with commas, so every value is
+	// kept in MultiValueHeaders, which API Gateway prefers over Headers.
+	for key, values := range w.header {
+		res.MultiValueHeaders[key] = append([]string(nil), values...)
+		if len(values) == 1 {
+			res.Headers[key] = values[0]
+		}
+	}
+	if utf8.Valid(w.body.Bytes()) {
+		res.Body = w.body.String()
+	} else {
+		res.Body = encodeBase64(w.body.Bytes())
+		res.IsBase64Encoded = true
+	}
+	return res
+}
+
+// readBody reads at most limit+1 bytes from body, which is enough to detect
+// an oversized request without buffering all of it. A limit of 0 reads everything.
+func readBody(body io.Reader, limit int64) string {
+	if limit > 0 {
+		body = io.LimitReader(body, limit+1)
+	}
+	b, _ := ioutil.ReadAll(body)
+	return string(b)
+}
+
+// limitBody returns body, failing reads past limit bytes. A limit of 0 does not
+// limit anything.
+func limitBody(body io.ReadCloser, limit int64) io.Reader {
+	if limit <= 0 {
+		return body
+	}
+	return http.MaxBytesReader(nil, body, limit)
+}
+
+// guardedBody is the body streamed to handlers, which stops serving reads once the
+// request is over. A handler which outlived its timeout may still be reading it while
+// the body is drained and closed.
+type guardedBody struct {
+	mutex   sync.Mutex
+	body    io.Reader
+	stopped bool
+}
+
+func (b *guardedBody) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.stopped {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	return b.body.Read(p)
+}
+
+// stop waits for the read in progress, if any, and fails the following ones.
+func (b *guardedBody) stop() {
+	b.mutex.Lock()
+	b.stopped = true
+	b.mutex.Unlock()
+}
+
+// maxDrainBytes bounds how much of an unread request body drainBody consumes, like
+// net/http does, so that a huge body does not keep the server busy.
+const maxDrainBytes = 256 << 10
+
+// drainBody reads what is left of body and closes it, which lets the connection be
+// reused for the next request when keep-alive is on.
+func drainBody(body io.ReadCloser) {
+	io.CopyN(ioutil.Discard, body, maxDrainBytes)
+	body.Close()
+}
+
+// StreamResponseThreshold is the body size above which ResToHttp writes a response
+// in chunks instead of all at once, which avoids holding a decoded copy of large
+// base64 bodies in memory. A value of 0 disables streaming.
+var StreamResponseThreshold = 64 * 1024
+
+const streamChunkSize = 32 * 1024
+
+func ResToHttp(w http.ResponseWriter, req *http.Request, res events.APIGatewayProxyResponse) {
+	stream := StreamResponseThreshold > 0 && len(res.Body) > StreamResponseThreshold
+	var data []byte
 	if res.IsBase64Encoded {
-		data, err := base64.StdEncoding.DecodeString(res.Body)
+		// Check the body before anything is written, so that a bad one turns into a
+		// clean 500 instead of a garbled response with the status of the handler.
+		var err error
+		if stream {
+			_, err = decodeBase64(ioutil.Discard, res.Body)
+		} else {
+			data, err = base64.StdEncoding.DecodeString(res.Body)
+		}
 		if err != nil {
-			w.Write([]byte(fmt.Sprintf("Error on decoding base64: %s\n", err.Error())))
+			fmt.Printf("Error on decoding base64: %s\n", err.Error())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal Server Error"}`))
 			return
 		}
-		w.Write(data)
+	} else if !stream {
+		data = []byte(res.Body)
+	}
+
+	for key := range res.Headers {
+		w.Header().Set(key, res.Headers[key])
+	}
+	for key, values := range res.MultiValueHeaders {
+		// Like API Gateway, the values of MultiValueHeaders replace the one of Headers.
+		w.Header().Del(key)
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(res.StatusCode)
+	if stream {
+		streamBody(w, res)
+		return
+	}
+	w.Write(data)
+}
+
+func streamBody(w io.Writer, res events.APIGatewayProxyResponse) {
+	if !res.IsBase64Encoded {
+		// Hide the WriterTo of strings.Reader so that io.CopyBuffer goes through the buffer.
+		io.CopyBuffer(w, struct{ io.Reader }{strings.NewReader(res.Body)}, make([]byte, streamChunkSize))
 		return
 	}
-	w.Write([]byte(res.Body))
+	if _, err := decodeBase64(w, res.Body); err != nil {
+		w.Write([]byte(fmt.Sprintf("Error on decoding base64: %s\n", err.Error())))
+	}
+}
+
+// encodeBase64 returns data encoded with standard base64. The encoding is written
+// straight into the returned string, where base64.StdEncoding.EncodeToString holds
+// a second copy of it, which matters for large binary responses.
+func encodeBase64(data []byte) string {
+	var b strings.Builder
+	b.Grow(base64.StdEncoding.EncodedLen(len(data)))
+	encoder := base64.NewEncoder(base64.StdEncoding, &b)
+	encoder.Write(data)
+	encoder.Close()
+	return b.String()
+}
+
+// decodeBase64 writes the decoding of the standard base64 s to w, streamChunkSize
+// bytes at a time, instead of holding all of it in memory.
+func decodeBase64(w io.Writer, s string) (int64, error) {
+	decoder := base64.NewDecoder(base64.StdEncoding, strings.NewReader(s))
+	// Hide the ReaderFrom of w, if any, so that io.CopyBuffer goes through the buffer.
+	return io.CopyBuffer(struct{ io.Writer }{w}, decoder, make([]byte, streamChunkSize))
 }
 
+// HttpAddParams sets the path parameters of event.
+//
+// Deprecated: assign event.PathParameters directly.
 func HttpAddParams(event events.APIGatewayProxyRequest, params map[string]string) events.APIGatewayProxyRequest {
+	warnDeprecated("HttpAddParams", "event.PathParameters")
 	event.PathParameters = params
 	return event
 }
@@ -132,17 +533,72 @@ func UseTemplate(event events.APIGatewayProxyRequest) string {
 	return string(out.Bytes())
 }
 
+// resourcePath converts a router pattern to the API Gateway resource it stands for,
+// such as /users/{id} for /users/:id and /files/{path+} for /files/*path.
+func resourcePath(pattern string) string {
+	var segments []string
+	for _, segment := range splitPattern(pattern) {
+		switch {
+		case segment.wildcard:
+			segments = append(segments, "{"+segment.text+"}")
+		case segment.catchAll:
+			segments = append(segments, "{"+segment.text+"+}")
+		default:
+			segments = append(segments, segment.text)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// CleanPath rebuilds the request path from its resource template.
+//
+// Deprecated: this does not clean anything, unlike Clean. Use UseTemplate.
 func CleanPath(event events.APIGatewayProxyRequest) string {
+	warnDeprecated("CleanPath", "UseTemplate")
 	return UseTemplate(event)
 }
 
+// DeprecationLogger receives a notice the first time each deprecated helper is
+// used. Set it to nil to silence the notices.
+var DeprecationLogger = log.New(os.Stderr, "lambdarouter: ", log.LstdFlags)
+
+var (
+	deprecationMutex  sync.Mutex
+	deprecationWarned = map[string]bool{}
+)
+
+func warnDeprecated(name, replacement string) {
+	deprecationMutex.Lock()
+	defer deprecationMutex.Unlock()
+	if DeprecationLogger == nil || deprecationWarned[name] {
+		return
+	}
+	deprecationWarned[name] = true
+	DeprecationLogger.Printf("%s is deprecated and will be removed, use %s instead", name, replacement)
+}
+
+// GenerateArn returns the ARN of the method invoked by event, in the form API Gateway
+// passes to authorizers: arn:aws:execute-api:region:account:api/stage/METHOD/path.
+// The region and account come from the AWS_REGION and AWS_ACCOUNT_ID variables,
+// and the local stage segment is left out of the path.
 func GenerateArn(event events.APIGatewayProxyRequest) string {
-	return fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/*/%s/%s", os.Getenv("AWS_REGION"), os.Getenv("AWS_ACCOUNT_ID"), "localhost", event.HTTPMethod, event.Path)
+	apiID, stage, path := event.RequestContext.APIID, event.RequestContext.Stage, event.Path
+	if apiID == "" {
+		apiID = LocalAPIID
+	}
+	if stage == "" {
+		stage = "*"
+	} else if trimmed := strings.TrimPrefix(path, "/"+stage); trimmed != path && (trimmed == "" || trimmed[0] == '/') {
+		path = trimmed
+	}
+	return fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/%s/%s/%s", os.Getenv("AWS_REGION"), os.Getenv("AWS_ACCOUNT_ID"), apiID, stage, event.HTTPMethod, strings.TrimPrefix(path, "/"))
 }
 
 func GenerateLambdaAuthorizer(event events.APIGatewayProxyRequest) events.APIGatewayCustomAuthorizerRequestTypeRequest {
 	return events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		Type:                            "REQUEST",
 		MethodArn:                       GenerateArn(event),
+		Resource:                        event.Resource,
 		Path:                            event.Path,
 		HTTPMethod:                      event.HTTPMethod,
 		Headers:                         event.Headers,
@@ -151,5 +607,17 @@ func GenerateLambdaAuthorizer(event events.APIGatewayProxyRequest) events.APIGat
 		MultiValueQueryStringParameters: event.MultiValueQueryStringParameters,
 		PathParameters:                  event.PathParameters,
 		StageVariables:                  event.StageVariables,
+		RequestContext: events.APIGatewayCustomAuthorizerRequestTypeRequestContext{
+			Path:         event.Path,
+			AccountID:    event.RequestContext.AccountID,
+			Stage:        event.RequestContext.Stage,
+			RequestID:    event.RequestContext.RequestID,
+			ResourcePath: event.RequestContext.ResourcePath,
+			HTTPMethod:   event.HTTPMethod,
+			APIID:        event.RequestContext.APIID,
+			Identity: events.APIGatewayCustomAuthorizerRequestTypeRequestIdentity{
+				SourceIP: event.RequestContext.Identity.SourceIP,
+			},
+		},
 	}
 }
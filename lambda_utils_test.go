@@ -0,0 +1,337 @@
+package lambdarouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestDeprecationWarnedOnce(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(l *log.Logger) { DeprecationLogger = l }(DeprecationLogger)
+	DeprecationLogger = log.New(&buf, "", 0)
+	deprecationWarned = map[string]bool{}
+
+	event := events.APIGatewayProxyRequest{Resource: "/abc"}
+	for i := 0; i < 3; i++ {
+		HttpAddParams(event, map[string]string{"id": "1"})
+		CleanPath(event)
+	}
+
+	if count := strings.Count(buf.String(), "HttpAddParams is deprecated"); count != 1 {
+		t.Errorf("Expected HttpAddParams deprecation to be logged once, saw %d in %q", count, buf.String())
+	}
+	if count := strings.Count(buf.String(), "CleanPath is deprecated"); count != 1 {
+		t.Errorf("Expected CleanPath deprecation to be logged once, saw %d in %q", count, buf.String())
+	}
+	if !strings.Contains(buf.String(), "use UseTemplate instead") {
+		t.Errorf("Expected CleanPath deprecation to point to UseTemplate, saw %q", buf.String())
+	}
+}
+
+func largeResponses() []events.APIGatewayProxyResponse {
+	data := bytes.Repeat([]byte("0123456789abcdef\x00\xff"), 64*1024)
+	return []events.APIGatewayProxyResponse{
+		{StatusCode: 200, Body: string(data)},
+		Binary(data, "application/octet-stream", 200),
+	}
+}
+
+func TestResToHttpStreaming(t *testing.T) {
+	defer func(threshold int) { StreamResponseThreshold = threshold }(StreamResponseThreshold)
+
+	for _, res := range largeResponses() {
+		StreamResponseThreshold = 0
+		single := httptest.NewRecorder()
+		ResToHttp(single, nil, res)
+
+		StreamResponseThreshold = 1024
+		streamed := httptest.NewRecorder()
+		ResToHttp(streamed, nil, res)
+
+		if !bytes.Equal(single.Body.Bytes(), streamed.Body.Bytes()) {
+			t.Errorf("Streamed body (base64 %v) differs from the single write", res.IsBase64Encoded)
+		}
+		if streamed.Code != res.StatusCode {
+			t.Errorf("Expected streamed status %d, saw %d", res.StatusCode, streamed.Code)
+		}
+	}
+}
+
+func TestResToHttpInvalidBase64(t *testing.T) {
+	defer func(threshold int) { StreamResponseThreshold = threshold }(StreamResponseThreshold)
+
+	for _, threshold := range []int{0, 8} {
+		StreamResponseThreshold = threshold
+		w := httptest.NewRecorder()
+		ResToHttp(w, nil, events.APIGatewayProxyResponse{
+			StatusCode:      200,
+			Headers:         map[string]string{"Content-Type": "image/png"},
+			Body:            "not base64 at all!",
+			IsBase64Encoded: true,
+		})
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Threshold %d expected status 500, saw %d", threshold, w.Code)
+		}
+		if w.Header().Get("Content-Type") != "application/json" || w.Body.String() != `{"error": "Internal Server Error"}` {
+			t.Errorf("Threshold %d expected a JSON error body, saw %q of type %q", threshold, w.Body.String(), w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestMultiValueHeaders(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/abc", nil)
+	r.Header.Add("X-Custom", "one")
+	r.Header.Add("X-Custom", "two")
+	req, _ := RequestToLambda(r)
+	if values := req.MultiValueHeaders["X-Custom"]; len(values) != 2 || values[0] != "one" || values[1] != "two" {
+		t.Errorf("Expected both X-Custom values, saw %v", values)
+	}
+	if req.Headers["X-Custom"] != "one" {
+		t.Errorf("Expected the first X-Custom value in Headers, saw %q", req.Headers["X-Custom"])
+	}
+	if values := req.MultiValueHeaders["X-Forwarded-Proto"]; len(values) != 1 || values[0] != "http" {
+		t.Errorf("Expected the headers set by the router in MultiValueHeaders, saw %v", values)
+	}
+
+	w := httptest.NewRecorder()
+	ResToHttp(w, r, events.APIGatewayProxyResponse{
+		StatusCode:        200,
+		Headers:           map[string]string{"Content-Type": "text/plain"},
+		MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+	})
+	if cookies := w.Header()["Set-Cookie"]; len(cookies) != 2 || cookies[0] != "a=1" || cookies[1] != "b=2" {
+		t.Errorf("Expected both Set-Cookie values, saw %v", cookies)
+	}
+	if w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected the single value headers too, saw %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	ResToHttp(w, r, events.APIGatewayProxyResponse{
+		StatusCode:        200,
+		Headers:           map[string]string{"Vary": "Origin", "X-Single": "one"},
+		MultiValueHeaders: map[string][]string{"vary": {"Origin", "Accept"}},
+	})
+	if vary := w.Header()["Vary"]; len(vary) != 2 || vary[0] != "Origin" || vary[1] != "Accept" {
+		t.Errorf("Expected MultiValueHeaders to replace the value of Headers, saw %v", vary)
+	}
+	if w.Header().Get("X-Single") != "one" {
+		t.Errorf("Expected the other headers to be kept, saw %v", w.Header())
+	}
+}
+
+func TestRawQueryString(t *testing.T) {
+	var raw string
+	router := New()
+	router.GET("/search", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		raw = RawQueryString(ctx, req)
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	query := "z=1&a=2&a=%7e&sig=abc%2Bdef"
+	r, _ := http.NewRequest("GET", "/__stage__/search?"+query, nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if raw != query {
+		t.Errorf("Expected the raw query %q, saw %q", query, raw)
+	}
+
+	// Without the original query, it is rebuilt from the parameters.
+	req := events.APIGatewayProxyRequest{
+		QueryStringParameters:           map[string]string{"z": "1", "a": "~"},
+		MultiValueQueryStringParameters: map[string][]string{"z": {"1"}, "a": {"2", "~"}},
+	}
+	if got := RawQueryString(context.Background(), req); got != "a=2&a=~&z=1" {
+		t.Errorf("Expected the rebuilt query a=2&a=~&z=1, saw %q", got)
+	}
+	req.MultiValueQueryStringParameters = nil
+	if got := RawQueryString(context.Background(), req); got != "a=~&z=1" {
+		t.Errorf("Expected the rebuilt query a=~&z=1, saw %q", got)
+	}
+}
+
+func TestIsTLS(t *testing.T) {
+	tests := []struct {
+		headers  map[string]string
+		expected bool
+	}{
+		{map[string]string{"X-Forwarded-Proto": "https"}, true},
+		{map[string]string{"x-forwarded-proto": "HTTPS"}, true},
+		{map[string]string{"X-Forwarded-Proto": "https, http"}, true},
+		{map[string]string{"X-Forwarded-Proto": "http"}, false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if got := IsTLS(events.APIGatewayProxyRequest{Headers: test.headers}); got != test.expected {
+			t.Errorf("Headers %v expected IsTLS %v, saw %v", test.headers, test.expected, got)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "https://example.com/abc", nil)
+	event, _ := RequestToLambda(r)
+	if !IsTLS(event) || event.RequestContext.Protocol != "HTTP/1.1" {
+		t.Errorf("Expected a TLS HTTP/1.1 request, saw TLS %v and protocol %q", IsTLS(event), event.RequestContext.Protocol)
+	}
+	r = httptest.NewRequest("GET", "http://example.com/abc", nil)
+	if event, _ = RequestToLambda(r); IsTLS(event) {
+		t.Error("Expected a plain HTTP request not to be TLS")
+	}
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if event, _ = RequestToLambda(r); IsTLS(event) || event.MultiValueHeaders["X-Forwarded-Proto"][0] != "http" {
+		t.Errorf("Expected a spoofed X-Forwarded-Proto to be overwritten, saw %v", event.MultiValueHeaders["X-Forwarded-Proto"])
+	}
+}
+
+func TestHeader(t *testing.T) {
+	var local events.APIGatewayProxyRequest
+	router := New()
+	router.POST("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		local = req
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	r, _ := http.NewRequest("POST", "/__stage__/abc", nil)
+	r.Header.Set("content-type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	lambda := events.APIGatewayProxyRequest{
+		Headers:           map[string]string{"content-type": "application/json"},
+		MultiValueHeaders: map[string][]string{"x-multi": {"a", "b"}},
+	}
+
+	for name, req := range map[string]events.APIGatewayProxyRequest{"local": local, "lambda": lambda} {
+		for _, header := range []string{"content-type", "Content-Type", "CONTENT-TYPE"} {
+			if got := Header(req, header); got != "application/json" {
+				t.Errorf("%s request expected %s application/json, saw %q", name, header, got)
+			}
+		}
+		if got := Header(req, "X-Missing"); got != "" {
+			t.Errorf("%s request expected no value for a missing header, saw %q", name, got)
+		}
+	}
+	if got := Header(lambda, "X-Multi"); got != "a" {
+		t.Errorf("Expected the first value of a multi-value header, saw %q", got)
+	}
+}
+
+func TestBase64RoundTrip(t *testing.T) {
+	large := bytes.Repeat([]byte("0123456789abcdef\x00\xff"), 64*1024)
+	for _, data := range [][]byte{nil, {0}, {0, 1}, {0, 1, 2}, large, large[:len(large)-1]} {
+		encoded := encodeBase64(data)
+		if expected := base64.StdEncoding.EncodeToString(data); encoded != expected {
+			t.Errorf("Encoding of %d bytes differs from the standard one", len(data))
+			continue
+		}
+		var decoded bytes.Buffer
+		n, err := decodeBase64(&decoded, encoded)
+		if err != nil || n != int64(len(data)) || !bytes.Equal(decoded.Bytes(), data) {
+			t.Errorf("Round trip of %d bytes gave %d bytes, %v", len(data), n, err)
+		}
+	}
+
+	if _, err := decodeBase64(ioutil.Discard, "not base64!"); err == nil {
+		t.Error("Expected an error decoding invalid base64")
+	}
+}
+
+func BenchmarkBinaryLarge(b *testing.B) {
+	data := bytes.Repeat([]byte("0123456789abcdef\x00\xff"), 64*1024)
+
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Binary(data, "application/octet-stream", 200)
+		}
+	})
+
+	body := Binary(data, "application/octet-stream", 200).Body
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			decodeBase64(ioutil.Discard, body)
+		}
+	})
+}
+
+func BenchmarkResToHttpLarge(b *testing.B) {
+	defer func(threshold int) { StreamResponseThreshold = threshold }(StreamResponseThreshold)
+	res := largeResponses()[1]
+	w := new(mockResponseWriter)
+
+	b.Run("single", func(b *testing.B) {
+		StreamResponseThreshold = 0
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ResToHttp(w, nil, res)
+		}
+	})
+
+	b.Run("streamed", func(b *testing.B) {
+		StreamResponseThreshold = 1024
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ResToHttp(w, nil, res)
+		}
+	})
+}
+
+func TestAPIIDAndResourcePath(t *testing.T) {
+	var seen events.APIGatewayProxyRequest
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		seen = req
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+	}
+
+	local := New()
+	local.GET("/users/:id", handler)
+	r, _ := http.NewRequest("GET", "/prod/users/5", nil)
+	local.ServeHTTP(httptest.NewRecorder(), r)
+	if id := APIID(seen); id != LocalAPIID {
+		t.Errorf("Expected the local API id, saw %q", id)
+	}
+	if path := ResourcePath(seen); path != "/users/{id}" {
+		t.Errorf("Expected /users/{id} locally, saw %q", path)
+	}
+	resourceID := seen.RequestContext.ResourceID
+	if len(resourceID) != 6 {
+		t.Errorf("Expected a 6 characters resource id, saw %q", resourceID)
+	}
+	local.ServeHTTP(httptest.NewRecorder(), r)
+	if seen.RequestContext.ResourceID != resourceID {
+		t.Errorf("Expected the same resource id for the same route, saw %q and %q", resourceID, seen.RequestContext.ResourceID)
+	}
+
+	router := newLambdaRouter()
+	router.GET("/users/:id", handler)
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Resource:       "/{proxy+}",
+		Path:           "/users/5",
+		PathParameters: map[string]string{"proxy": "users/5"},
+	}
+	req.RequestContext.APIID = "a1b2c3d4e5"
+	req.RequestContext.ResourcePath = "/{proxy+}"
+	router.ServeLambda(context.Background(), req)
+	if id := APIID(seen); id != "a1b2c3d4e5" {
+		t.Errorf("Expected the API id of API Gateway, saw %q", id)
+	}
+	if path := ResourcePath(seen); path != "/users/{id}" {
+		t.Errorf("Expected /users/{id} behind a proxy resource, saw %q", path)
+	}
+
+	req.Resource = "/users/{id}"
+	req.RequestContext.ResourcePath = "/users/{id}"
+	req.PathParameters = map[string]string{"id": "5"}
+	router.ServeLambda(context.Background(), req)
+	if path := ResourcePath(seen); path != "/users/{id}" {
+		t.Errorf("Expected the resource of API Gateway, saw %q", path)
+	}
+}
@@ -0,0 +1,65 @@
+package lambdarouter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// maintenance holds the state set with SetMaintenance, which may change while
+// requests are served.
+type maintenance struct {
+	mutex      sync.RWMutex
+	on         bool
+	retryAfter time.Duration
+	allowed    map[string]bool
+}
+
+// SetMaintenance turns the maintenance mode on or off. While it is on, every request
+// is answered with 503 Service Unavailable and a Retry-After header of retryAfter,
+// except the ones to patterns given to MaintenanceAllow, such as health checks. It
+// is safe to call while serving requests.
+func (t *TreeMux) SetMaintenance(on bool, retryAfter time.Duration) {
+	t.maintenance.mutex.Lock()
+	defer t.maintenance.mutex.Unlock()
+	t.maintenance.on = on
+	t.maintenance.retryAfter = retryAfter
+}
+
+// MaintenanceAllow lets requests to the given patterns, as they were registered,
+// reach their handlers during maintenance.
+//
+//	router.MaintenanceAllow("/health")
+func (t *TreeMux) MaintenanceAllow(patterns ...string) {
+	t.maintenance.mutex.Lock()
+	defer t.maintenance.mutex.Unlock()
+	if t.maintenance.allowed == nil {
+		t.maintenance.allowed = make(map[string]bool)
+	}
+	for _, pattern := range patterns {
+		t.maintenance.allowed[pattern] = true
+	}
+}
+
+// inMaintenance reports whether the request matching pattern must be turned away,
+// and how long the client should wait before retrying.
+func (t *TreeMux) inMaintenance(pattern string) (time.Duration, bool) {
+	t.maintenance.mutex.RLock()
+	defer t.maintenance.mutex.RUnlock()
+	if !t.maintenance.on || (pattern != "" && t.maintenance.allowed[pattern]) {
+		return 0, false
+	}
+	return t.maintenance.retryAfter, true
+}
+
+func serviceUnavailable(ctx context.Context, req events.APIGatewayProxyRequest, retryAfter time.Duration) (events.APIGatewayProxyResponse, error) {
+	res, err := LambdaServiceUnavailable(ctx, req)
+	if retryAfter > 0 {
+		seconds := (retryAfter + time.Second - 1) / time.Second
+		res.Headers = map[string]string{"Retry-After": strconv.FormatInt(int64(seconds), 10)}
+	}
+	return res, err
+}
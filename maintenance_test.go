@@ -0,0 +1,41 @@
+package lambdarouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMaintenance(t *testing.T) {
+	router := New()
+	router.GET("/user", simpleHandler)
+	router.GET("/health", simpleHandler)
+	router.MaintenanceAllow("/health")
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/__stage__"+path, nil)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	router.SetMaintenance(true, 90*time.Second)
+	for _, path := range []string{"/user", "/missing"} {
+		w := serve(path)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s during maintenance expected status 503, saw %d", path, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "90" {
+			t.Errorf("%s during maintenance expected Retry-After 90, saw %q", path, got)
+		}
+	}
+	if w := serve("/health"); w.Code != http.StatusNoContent {
+		t.Errorf("Health check during maintenance expected status 204, saw %d", w.Code)
+	}
+
+	router.SetMaintenance(false, 0)
+	if w := serve("/user"); w.Code != http.StatusNoContent || w.Header().Get("Retry-After") != "" {
+		t.Errorf("Expected normal operation after maintenance, saw status %d", w.Code)
+	}
+}
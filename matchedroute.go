@@ -0,0 +1,20 @@
+package lambdarouter
+
+import "github.com/aws/aws-lambda-go/events"
+
+// EnableMatchedRouteHeader adds an X-Matched-Route header with the pattern of the
+// matched route, without the local stage, to the responses of the handlers. It
+// helps finding which of overlapping routes served a request, and is off by default
+// so as not to expose the routes of the API.
+//
+//	X-Matched-Route: /users/:id
+func (t *TreeMux) EnableMatchedRouteHeader() {
+	t.matchedRouteHeader = true
+}
+
+func setMatchedRouteHeader(res *events.APIGatewayProxyResponse, pattern string) {
+	if res.Headers == nil {
+		res.Headers = map[string]string{}
+	}
+	res.Headers["X-Matched-Route"] = pattern
+}
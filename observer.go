@@ -0,0 +1,47 @@
+package lambdarouter
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ObservationData describes a request served by the router, for the observer set
+// with SetObserver.
+type ObservationData struct {
+	Method     string
+	Path       string
+	Pattern    string  // The pattern of the matched route, empty when none matched
+	Outcome    Outcome // Tells not found requests from the ones with a wrong method
+	StatusCode int
+	// AllowedMethods lists the methods registered on the path, when Outcome is
+	// MethodNotAllowed.
+	AllowedMethods []string
+	// Duration is the time spent serving the request, the handler included.
+	Duration time.Duration
+}
+
+// SetObserver sets a function called after each request served by ServeHTTP,
+// ServeLambda or ServeLookupResult, to feed metrics or logs. Counting the
+// MethodNotAllowed outcomes apart from the NotFound ones tells misconfigured
+// clients from probing. Passing nil removes the observer.
+func (t *TreeMux) SetObserver(observer func(ctx context.Context, data ObservationData)) {
+	t.observer = observer
+}
+
+func (t *TreeMux) observe(ctx context.Context, req events.APIGatewayProxyRequest, lr LookupResult, res events.APIGatewayProxyResponse, d time.Duration) {
+	data := ObservationData{
+		Method:     req.HTTPMethod,
+		Path:       req.Path,
+		Pattern:    lr.MatchedPattern,
+		Outcome:    lr.Outcome,
+		StatusCode: res.StatusCode,
+		Duration:   d,
+	}
+	if lr.Outcome == MethodNotAllowed && lr.allow != "" {
+		data.AllowedMethods = strings.Split(lr.allow, " ")
+	}
+	t.observer(ctx, data)
+}
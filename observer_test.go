@@ -0,0 +1,47 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestObserver(t *testing.T) {
+	var observed []ObservationData
+	router := newLambdaRouter()
+	router.GET("/users/:id", simpleHandler)
+	router.PUT("/users/:id", simpleHandler)
+	router.SetObserver(func(ctx context.Context, data ObservationData) {
+		observed = append(observed, data)
+	})
+
+	for _, req := range []events.APIGatewayProxyRequest{
+		{HTTPMethod: "GET", Resource: "/users/5", Path: "/users/5"},
+		{HTTPMethod: "DELETE", Resource: "/users/5", Path: "/users/5"},
+		{HTTPMethod: "GET", Resource: "/admin", Path: "/admin"},
+	} {
+		router.ServeLambda(context.Background(), req)
+	}
+	if len(observed) != 3 {
+		t.Fatalf("Expected 3 observations, saw %d", len(observed))
+	}
+
+	if data := observed[0]; data.Outcome != Matched || data.Pattern != "/users/:id" || data.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected a match of /users/:id, saw %+v", data)
+	}
+
+	notAllowed := observed[1]
+	if notAllowed.Outcome != MethodNotAllowed || notAllowed.Method != "DELETE" || notAllowed.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected DELETE not to be allowed, saw %+v", notAllowed)
+	}
+	if expected := []string{"GET", "HEAD", "PUT"}; !reflect.DeepEqual(notAllowed.AllowedMethods, expected) {
+		t.Errorf("Expected the allowed methods %v, saw %v", expected, notAllowed.AllowedMethods)
+	}
+
+	if data := observed[2]; data.Outcome != NotFound || data.Path != "/admin" || data.AllowedMethods != nil {
+		t.Errorf("Expected /admin not to be found, saw %+v", data)
+	}
+}
@@ -0,0 +1,106 @@
+package lambdarouter
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// OpenAPIInfo is the info object of the document generated by OpenAPI.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openAPIDocument struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    OpenAPIInfo                            `json:"info"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// OpenAPI returns an OpenAPI 3 document describing the registered routes, as a
+// starting point for API Gateway definitions. Wildcards and catch-alls become
+// {param} path parameters, each method an operation with a default 200 response,
+// and named routes use their name as operationId. The HEAD operations added
+// implicitly for GET routes are left out, as is the local stage.
+func (t *TreeMux) OpenAPI(info OpenAPIInfo) ([]byte, error) {
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+
+	names := make(map[*Route]string, len(t.namedRoutes))
+	for name, route := range t.namedRoutes {
+		names[route] = name
+	}
+
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]map[string]openAPIOperation{},
+	}
+	t.root.walk(func(n *node) {
+		n.eachRoute(func(method string, route *Route) {
+			if route.method != method {
+				return
+			}
+			path, params := openAPIPath(route.path)
+			operations, ok := doc.Paths[path]
+			if !ok {
+				operations = map[string]openAPIOperation{}
+				doc.Paths[path] = operations
+			}
+			if _, exists := operations[strings.ToLower(method)]; exists {
+				// Routes told apart by headers share the operation of the first one.
+				return
+			}
+			operations[strings.ToLower(method)] = openAPIOperation{
+				OperationID: names[route],
+				Parameters:  params,
+				Responses:   map[string]openAPIResponse{"200": {Description: "OK"}},
+			}
+		})
+	})
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath converts a router pattern to an OpenAPI path and its parameters.
+func openAPIPath(pattern string) (string, []openAPIParameter) {
+	var params []openAPIParameter
+	var segments []string
+	for _, segment := range splitPattern(pattern) {
+		if !segment.wildcard && !segment.catchAll {
+			segments = append(segments, segment.text)
+			continue
+		}
+		segments = append(segments, "{"+segment.text+"}")
+		schema := map[string]string{"type": "string"}
+		if segment.expr != "" {
+			schema["pattern"] = "^(?:" + segment.expr + ")$"
+		}
+		params = append(params, openAPIParameter{
+			Name:     segment.text,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,74 @@
+package lambdarouter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	router := New()
+	router.GET("/users", simpleHandler).Name("listUsers")
+	router.POST("/users", simpleHandler)
+	router.GET("/users/:id", simpleHandler)
+	router.DELETE("/users/:id", simpleHandler)
+	router.GET("/files/:bucket/*path", simpleHandler)
+
+	data, err := router.OpenAPI(OpenAPIInfo{Title: "Test API", Version: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string
+		Info    OpenAPIInfo
+		Paths   map[string]map[string]struct {
+			OperationID string
+			Parameters  []struct{ Name, In string }
+			Responses   map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Title != "Test API" || doc.Info.Version != "1.0" {
+		t.Errorf("Unexpected header %s %+v", doc.OpenAPI, doc.Info)
+	}
+
+	expected := map[string][]string{
+		"/users":                 {"get", "post"},
+		"/users/{id}":            {"delete", "get"},
+		"/files/{bucket}/{path}": {"get"},
+	}
+	for path, methods := range expected {
+		operations, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("Expected the path %s in %s", path, data)
+			continue
+		}
+		for _, method := range methods {
+			if _, ok := operations[method].Responses["200"]; !ok {
+				t.Errorf("Expected %s %s with a 200 response", method, path)
+			}
+		}
+		if len(operations) != len(methods) {
+			t.Errorf("Expected only %v for %s, saw %d operations", methods, path, len(operations))
+		}
+	}
+	if len(doc.Paths) != len(expected) {
+		t.Errorf("Expected %d paths, saw %d", len(expected), len(doc.Paths))
+	}
+
+	if id := doc.Paths["/users"]["get"].OperationID; id != "listUsers" {
+		t.Errorf("Expected the route name as operationId, saw %q", id)
+	}
+	var names []string
+	for _, param := range doc.Paths["/files/{bucket}/{path}"]["get"].Parameters {
+		if param.In != "path" {
+			t.Errorf("Expected %s to be a path parameter, saw %s", param.Name, param.In)
+		}
+		names = append(names, param.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"bucket", "path"}) {
+		t.Errorf("Expected the parameters bucket and path, saw %v", names)
+	}
+}
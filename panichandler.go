@@ -2,12 +2,16 @@ package lambdarouter
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"net/http"
 	"os"
 	"runtime"
 	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
 )
 
 // SimplePanicHandler just returns error 500.
@@ -15,6 +19,15 @@ func SimplePanicHandler(w http.ResponseWriter, r *http.Request, err interface{})
 	w.WriteHeader(http.StatusInternalServerError)
 }
 
+// SimpleServeLambdaPanicHandler logs the panic with its stack trace and returns error 500.
+func SimpleServeLambdaPanicHandler(ctx context.Context, req events.APIGatewayProxyRequest, err interface{}, stack []byte) (events.APIGatewayProxyResponse, error) {
+	fmt.Printf("panic serving %s %s: %v\n%s", req.HTTPMethod, req.Path, err, stack)
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusInternalServerError,
+		Body:       `{"error": "Internal Server Error"}`,
+	}, nil
+}
+
 // ShowErrorsPanicHandler prints a nice representation of an error to the browser.
 // This was taken from github.com/gocraft/web, which adapted it from the Traffic project.
 func ShowErrorsPanicHandler(w http.ResponseWriter, r *http.Request, err interface{}) {
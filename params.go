@@ -0,0 +1,116 @@
+package lambdarouter
+
+// Transform registers fn to normalize the value of every path parameter named
+// param before it reaches the handler, for example to lowercase a username:
+//
+//	router.Transform("username", strings.ToLower)
+//
+// Transformers registered for the same name are applied in registration order.
+func (t *TreeMux) Transform(param string, fn func(string) string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.paramTransformers == nil {
+		t.paramTransformers = make(map[string][]func(string) string)
+	}
+	t.paramTransformers[param] = append(t.paramTransformers[param], fn)
+}
+
+func (t *TreeMux) transformParam(name, value string) string {
+	for _, fn := range t.paramTransformers[name] {
+		value = fn(value)
+	}
+	return value
+}
+
+// smallParams is the number of parameters a paramList holds without allocating.
+const smallParams = 4
+
+type paramPair struct {
+	name, value string
+}
+
+// paramList holds the parameters captured by a lookup. Most routes have only a
+// few parameters, which are kept in a fixed array instead of a map, so that a
+// lookup does not allocate one. Larger sets fall back to a map.
+type paramList struct {
+	small [smallParams]paramPair
+	n     int
+	large map[string]string
+}
+
+func newParamList(size int) paramList {
+	var p paramList
+	if size > smallParams {
+		p.large = make(map[string]string, size)
+	}
+	return p
+}
+
+func (p *paramList) set(name, value string) {
+	if p.large != nil {
+		p.large[name] = value
+		return
+	}
+	for i := 0; i < p.n; i++ {
+		if p.small[i].name == name {
+			p.small[i].value = value
+			return
+		}
+	}
+	if p.n == smallParams {
+		p.large = make(map[string]string, smallParams+1)
+		for _, pair := range p.small {
+			p.large[pair.name] = pair.value
+		}
+		p.large[name] = value
+		return
+	}
+	p.small[p.n] = paramPair{name, value}
+	p.n++
+}
+
+func (p *paramList) get(name string) (string, bool) {
+	if p.large != nil {
+		value, ok := p.large[name]
+		return value, ok
+	}
+	for i := 0; i < p.n; i++ {
+		if p.small[i].name == name {
+			return p.small[i].value, true
+		}
+	}
+	return "", false
+}
+
+func (p *paramList) len() int {
+	if p.large != nil {
+		return len(p.large)
+	}
+	return p.n
+}
+
+// toMap returns the parameters as the map handlers receive, leaving out the
+// parameter named skip. It returns nil when there is nothing left.
+func (p *paramList) toMap(skip string) map[string]string {
+	var m map[string]string
+	add := func(name, value string) {
+		if name == skip {
+			return
+		}
+		if m == nil {
+			m = make(map[string]string, p.len())
+		}
+		m[name] = value
+	}
+	if p.large != nil {
+		for name, value := range p.large {
+			add(name, value)
+		}
+		return m
+	}
+	for i := 0; i < p.n; i++ {
+		add(p.small[i].name, p.small[i].value)
+	}
+	return m
+}
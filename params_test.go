@@ -0,0 +1,106 @@
+package lambdarouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestParamList(t *testing.T) {
+	for _, size := range []int{1, smallParams, smallParams + 1, 10} {
+		p := newParamList(size)
+		expected := map[string]string{}
+		for i := 0; i < size; i++ {
+			name, value := fmt.Sprintf("p%d", i), fmt.Sprintf("v%d", i)
+			p.set(name, value)
+			expected[name] = value
+		}
+
+		if p.len() != size {
+			t.Errorf("Size %d: expected len %d, saw %d", size, size, p.len())
+		}
+		for name, value := range expected {
+			if got, ok := p.get(name); !ok || got != value {
+				t.Errorf("Size %d: expected %s=%s, saw %q", size, name, value, got)
+			}
+		}
+		if _, ok := p.get("missing"); ok {
+			t.Errorf("Size %d: found a parameter that was never set", size)
+		}
+		if m := p.toMap(""); !reflect.DeepEqual(m, expected) {
+			t.Errorf("Size %d: expected map %v, saw %v", size, expected, m)
+		}
+		delete(expected, "p0")
+		if len(expected) == 0 {
+			expected = nil
+		}
+		if m := p.toMap("p0"); !reflect.DeepEqual(m, expected) {
+			t.Errorf("Size %d: expected map without p0 %v, saw %v", size, expected, m)
+		}
+	}
+
+	// A small list grows into a map when it runs out of room.
+	var p paramList
+	for i := 0; i <= smallParams; i++ {
+		p.set(fmt.Sprintf("p%d", i), "v")
+	}
+	p.set("p0", "changed")
+	if v, _ := p.get("p0"); v != "changed" || p.len() != smallParams+1 {
+		t.Errorf("Expected a grown list with p0=changed, saw p0=%s and len %d", v, p.len())
+	}
+}
+
+func TestParamListAccess(t *testing.T) {
+	var params map[string]string
+	router := New()
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		params = req.PathParameters
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+	router.GET("/users/:id", handler)
+	router.GET("/:a/:b/:c/:d/:e/:f", handler)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/__stage__/users/5", nil)
+	router.ServeHTTP(w, r)
+	if expected := map[string]string{"id": "5"}; !reflect.DeepEqual(params, expected) {
+		t.Errorf("Expected params %v, saw %v", expected, params)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/__stage__/1/2/3/4/5/6", nil)
+	router.ServeHTTP(w, r)
+	expected := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("Expected params %v, saw %v", expected, params)
+	}
+}
+
+func benchmarkLookup(b *testing.B, pattern, path string) {
+	router := newLambdaRouter()
+	router.GET(pattern, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, nil
+	})
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: path}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.Lookup(req)
+	}
+}
+
+// BenchmarkLookupOneParam does not allocate a map for the parameter, unlike
+// BenchmarkLookupManyParams which exceeds smallParams.
+func BenchmarkLookupOneParam(b *testing.B) {
+	benchmarkLookup(b, "/users/:id", "/users/5")
+}
+
+func BenchmarkLookupManyParams(b *testing.B) {
+	benchmarkLookup(b, "/:a/:b/:c/:d/:e/:f", "/1/2/3/4/5/6")
+}
@@ -0,0 +1,109 @@
+package lambdarouter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SetRateLimit allows at most limit requests to reach the handlers in every window
+// of the given duration. Requests beyond it get a 429 Too Many Requests response
+// carrying Retry-After and X-RateLimit-* headers, so that clients can back off.
+// A limit of 0 removes the rate limit.
+func (t *TreeMux) SetRateLimit(limit int, window time.Duration) {
+	if limit <= 0 || window <= 0 {
+		t.rateLimiter = nil
+		return
+	}
+	t.rateLimiter = &rateLimiter{limit: limit, window: window, now: time.Now}
+}
+
+// RateLimitConfig allows at most Limit requests in every Window.
+type RateLimitConfig struct {
+	Limit  int
+	Window time.Duration
+}
+
+// SetStageRateLimit sets the rate limit of the requests to the given stage, for
+// example to throttle prod while leaving dev alone. It takes precedence over the
+// limit set with SetRateLimit, which otherwise applies to every stage, and each
+// stage counts its requests separately. A Limit of 0 removes the stage limit.
+func (t *TreeMux) SetStageRateLimit(stage string, cfg RateLimitConfig) {
+	if cfg.Limit <= 0 || cfg.Window <= 0 {
+		delete(t.stageRateLimiters, stage)
+		return
+	}
+	if t.stageRateLimiters == nil {
+		t.stageRateLimiters = make(map[string]*rateLimiter)
+	}
+	t.stageRateLimiters[stage] = &rateLimiter{limit: cfg.Limit, window: cfg.Window, now: time.Now}
+}
+
+// rateLimiterFor returns the limiter for requests to stage, or nil when they are
+// not limited.
+func (t *TreeMux) rateLimiterFor(stage string) *rateLimiter {
+	if l, ok := t.stageRateLimiters[stage]; ok {
+		return l
+	}
+	return t.rateLimiter
+}
+
+// rateLimiter counts requests in fixed windows.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	start time.Time
+	count int
+}
+
+// rateLimitState describes the limiter after a request was counted.
+type rateLimitState struct {
+	limit     int
+	remaining int
+	reset     time.Time
+	retry     time.Duration
+}
+
+// allow counts a request and reports whether it fits in the current window.
+func (l *rateLimiter) allow() (rateLimitState, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if l.start.IsZero() || now.Sub(l.start) >= l.window {
+		l.start = now
+		l.count = 0
+	}
+
+	state := rateLimitState{limit: l.limit, reset: l.start.Add(l.window)}
+	if l.count >= l.limit {
+		state.retry = state.reset.Sub(now)
+		return state, false
+	}
+	l.count++
+	state.remaining = l.limit - l.count
+	return state, true
+}
+
+func (s rateLimitState) headers() map[string]string {
+	// Round up, so that a client retrying after Retry-After lands in the next window.
+	retry := (s.retry + time.Second - 1) / time.Second
+	return map[string]string{
+		"Retry-After":           strconv.FormatInt(int64(retry), 10),
+		"X-RateLimit-Limit":     strconv.Itoa(s.limit),
+		"X-RateLimit-Remaining": strconv.Itoa(s.remaining),
+		"X-RateLimit-Reset":     strconv.FormatInt(s.reset.Unix(), 10),
+	}
+}
+
+func rateLimited(ctx context.Context, req events.APIGatewayProxyRequest, state rateLimitState) (events.APIGatewayProxyResponse, error) {
+	res, err := LambdaTooManyRequests(ctx, req)
+	res.Headers = state.headers()
+	return res, err
+}
@@ -0,0 +1,99 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	router := newLambdaRouter()
+	router.GET("/user", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	router.SetRateLimit(2, time.Minute)
+	router.rateLimiter.now = func() time.Time { return now }
+
+	serve := func() events.APIGatewayProxyResponse {
+		req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/user", Path: "/user"}
+		res, _ := router.ServeLambda(context.Background(), req)
+		return res
+	}
+
+	for i := 0; i < 2; i++ {
+		if res := serve(); res.StatusCode != 200 {
+			t.Fatalf("Request %d expected status 200, saw %d", i, res.StatusCode)
+		}
+	}
+
+	now = now.Add(20 * time.Second)
+	res := serve()
+	if res.StatusCode != 429 {
+		t.Fatalf("Throttled request expected status 429, saw %d", res.StatusCode)
+	}
+	header := func(name string) int64 {
+		value, ok := res.Headers[name]
+		if !ok {
+			t.Errorf("Expected header %s", name)
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			t.Errorf("Header %s expected a number, saw %q", name, value)
+		}
+		return n
+	}
+	limit, remaining, reset, retry := header("X-RateLimit-Limit"), header("X-RateLimit-Remaining"), header("X-RateLimit-Reset"), header("Retry-After")
+	if limit != 2 || remaining != 0 {
+		t.Errorf("Expected limit 2 with 0 remaining, saw limit %d with %d remaining", limit, remaining)
+	}
+	if retry != 40 || reset != now.Unix()+retry {
+		t.Errorf("Expected a retry after 40s at %d, saw a retry after %ds at %d", now.Unix()+40, retry, reset)
+	}
+
+	now = now.Add(40 * time.Second)
+	if res := serve(); res.StatusCode != 200 {
+		t.Errorf("Request in the next window expected status 200, saw %d", res.StatusCode)
+	}
+}
+
+func TestStageRateLimit(t *testing.T) {
+	router := New()
+	router.GET("/user", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	router.SetStageRateLimit("prod", RateLimitConfig{Limit: 3, Window: time.Minute})
+
+	burst := func(stage string) (passed int) {
+		for i := 0; i < 5; i++ {
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", "/"+stage+"/user", nil)
+			router.ServeHTTP(w, r)
+			if w.Code == 200 {
+				passed++
+			}
+		}
+		return passed
+	}
+
+	if passed := burst("prod"); passed != 3 {
+		t.Errorf("Expected 3 requests of the burst to pass under prod, saw %d", passed)
+	}
+	if passed := burst("dev"); passed != 5 {
+		t.Errorf("Expected the whole burst to pass under dev, saw %d", passed)
+	}
+
+	// The global limit applies to the stages without their own.
+	router.SetRateLimit(1, time.Minute)
+	if passed := burst("dev"); passed != 1 {
+		t.Errorf("Expected 1 request of the burst to pass under dev with a global limit, saw %d", passed)
+	}
+	if passed := burst("prod"); passed != 0 {
+		t.Errorf("Expected prod to stay throttled by its own limit, saw %d passing", passed)
+	}
+}
@@ -0,0 +1,119 @@
+package lambdarouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Binary returns a response carrying data, base64-encoded and flagged with
+// IsBase64Encoded as API Gateway expects for binary content. ResToHttp decodes it
+// again when serving locally. ServeLambda encodes the binary bodies of responses
+// without the flag as well, but only after copying them into a string.
+func Binary(data []byte, contentType string, status int) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers: map[string]string{
+			"Content-Type": contentType,
+		},
+		Body:            encodeBase64(data),
+		IsBase64Encoded: true,
+	}
+}
+
+// JSON returns a response with the status and v marshaled as its body, flagged
+// with Content-Type: application/json. It can be returned from handlers as is:
+//
+//	return lambdarouter.JSON(http.StatusOK, user)
+//
+// When v can not be marshaled, the error is logged and the response is a 500 with
+// a generic JSON error, so that nothing of v leaks to the client. The returned
+// error is always nil.
+func JSON(status int, v interface{}) (events.APIGatewayProxyResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("Error on encoding JSON response: %s\n", err.Error())
+		return Error(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}, nil
+}
+
+// Error returns a JSON response with the status and a body holding message in an
+// "error" field, like the router's own error responses, marshaled without spaces as
+// in {"error":"not found"}.
+func Error(status int, message string) (events.APIGatewayProxyResponse, error) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}, nil
+}
+
+// encodeBinaryBody base64-encodes the body of res when a handler returned binary
+// data without setting IsBase64Encoded, as API Gateway would otherwise replace
+// every byte which is not valid UTF-8. Use Binary to encode it upfront.
+func encodeBinaryBody(res *events.APIGatewayProxyResponse) {
+	if res.IsBase64Encoded || utf8.ValidString(res.Body) {
+		return
+	}
+	res.Body = encodeBase64([]byte(res.Body))
+	res.IsBase64Encoded = true
+}
+
+// HTTPError is an error a handler can return to answer with a given response, for
+// example a 401 challenging the client:
+//
+//	return events.APIGatewayProxyResponse{}, HTTPError{401, "", map[string]string{"WWW-Authenticate": "Bearer"}}
+//
+// The router turns it into the response instead of failing the invocation. An empty
+// Body is replaced by a JSON error naming the status.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	Headers    map[string]string
+}
+
+func (e HTTPError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// response returns the response carried by e.
+func (e HTTPError) response() events.APIGatewayProxyResponse {
+	res := events.APIGatewayProxyResponse{StatusCode: e.StatusCode, Body: e.Body}
+	if res.Body == "" {
+		body, _ := json.Marshal(map[string]string{"error": http.StatusText(e.StatusCode)})
+		res.Body = string(body)
+	}
+	if len(e.Headers) > 0 {
+		res.Headers = make(map[string]string, len(e.Headers))
+		for key, value := range e.Headers {
+			res.Headers[key] = value
+		}
+	}
+	return res
+}
+
+// asHTTPError finds an HTTPError, or a pointer to one, in the chain of err.
+func asHTTPError(err error) (HTTPError, bool) {
+	var value HTTPError
+	if errors.As(err, &value) {
+		return value, true
+	}
+	var pointer *HTTPError
+	if errors.As(err, &pointer) && pointer != nil {
+		return *pointer, true
+	}
+	return HTTPError{}, false
+}
@@ -0,0 +1,129 @@
+package lambdarouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var pngHeader = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0x00, 0x00, 0x0d, 'I', 'H', 'D', 'R'}
+
+func TestBinary(t *testing.T) {
+	res := Binary(pngHeader, "image/png", http.StatusOK)
+
+	if !res.IsBase64Encoded {
+		t.Error("Expected IsBase64Encoded to be set")
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, saw %d", res.StatusCode)
+	}
+	if ct := res.Headers["Content-Type"]; ct != "image/png" {
+		t.Errorf("Expected Content-Type image/png, saw %q", ct)
+	}
+	if expected := base64.StdEncoding.EncodeToString(pngHeader); res.Body != expected {
+		t.Errorf("Expected body %q, saw %q", expected, res.Body)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/image.png", nil)
+	ResToHttp(w, r, res)
+	if !bytes.Equal(w.Body.Bytes(), pngHeader) {
+		t.Errorf("Expected ResToHttp to write the decoded image, saw %v", w.Body.Bytes())
+	}
+}
+
+func TestServeLambdaBinaryBody(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/raw.png", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "image/png"},
+			Body:       string(pngHeader),
+		}, nil
+	})
+	router.GET("/image.png", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return Binary(pngHeader, "image/png", http.StatusOK), nil
+	})
+	router.GET("/text", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "héllo"}, nil
+	})
+
+	for _, path := range []string{"/raw.png", "/image.png"} {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: path, Path: path})
+		if !res.IsBase64Encoded {
+			t.Errorf("%s expected IsBase64Encoded to be set", path)
+		}
+		w := httptest.NewRecorder()
+		ResToHttp(w, nil, res)
+		if !bytes.Equal(w.Body.Bytes(), pngHeader) {
+			t.Errorf("%s expected the image to round-trip, saw %v", path, w.Body.Bytes())
+		}
+	}
+
+	res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/text", Path: "/text"})
+	if res.IsBase64Encoded || res.Body != "héllo" {
+		t.Errorf("Expected text to be left as is, saw %q", res.Body)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	res, err := JSON(http.StatusCreated, user{Name: "bob", Age: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusCreated || res.Body != `{"name":"bob","age":42}` {
+		t.Errorf("Expected a 201 with bob, saw %d %s", res.StatusCode, res.Body)
+	}
+	if contentType := res.Headers["Content-Type"]; contentType != "application/json" {
+		t.Errorf("Expected application/json, saw %q", contentType)
+	}
+
+	res, err = JSON(http.StatusOK, map[string]interface{}{"callback": func() {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusInternalServerError || res.Body != `{"error":"Internal Server Error"}` {
+		t.Errorf("Expected a 500 for a value which can not be marshaled, saw %d %s", res.StatusCode, res.Body)
+	}
+
+	res, _ = Error(http.StatusNotFound, `no user "bob"`)
+	if res.StatusCode != http.StatusNotFound || res.Body != `{"error":"no user \"bob\""}` {
+		t.Errorf("Expected a 404 naming bob, saw %d %s", res.StatusCode, res.Body)
+	}
+	if contentType := res.Headers["Content-Type"]; contentType != "application/json" {
+		t.Errorf("Expected application/json, saw %q", contentType)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/private", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, HTTPError{401, "", map[string]string{"WWW-Authenticate": "Bearer"}}
+	})
+	router.GET("/wrapped", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, fmt.Errorf("loading: %w", HTTPError{StatusCode: 404, Body: `{"error": "no such item"}`})
+	})
+
+	res, err := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/private", Path: "/private"})
+	if err != nil {
+		t.Fatalf("Expected the error to become the response, saw %v", err)
+	}
+	if res.StatusCode != 401 || res.Headers["WWW-Authenticate"] != "Bearer" || res.Body != `{"error":"Unauthorized"}` {
+		t.Errorf("Expected a 401 challenging the client, saw %d %v %q", res.StatusCode, res.Headers, res.Body)
+	}
+
+	res, err = router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/wrapped", Path: "/wrapped"})
+	if err != nil || res.StatusCode != 404 || res.Body != `{"error": "no such item"}` {
+		t.Errorf("Expected the wrapped error to become a 404, saw %d %q (%v)", res.StatusCode, res.Body, err)
+	}
+}
@@ -0,0 +1,44 @@
+package lambdarouter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type rootRedirect struct {
+	target string
+	code   int
+}
+
+// SetRootRedirect redirects requests to the root path, such as / or /stage when
+// serving locally, to target with the given status code, for example to send
+// visitors to the documentation. It only applies when no handler is registered for
+// the root path. Locally, a target starting with a slash is kept under the stage
+// of the request. An empty target removes the redirect.
+func (t *TreeMux) SetRootRedirect(target string, code int) {
+	if target == "" {
+		t.rootRedirect = nil
+		return
+	}
+	t.rootRedirect = &rootRedirect{target: target, code: code}
+}
+
+// isRootPath reports whether path is the root path, which includes the stage
+// segment when serving locally.
+func (t *TreeMux) isRootPath(path string) bool {
+	path = strings.Trim(path, "/")
+	if t.path == "" {
+		return path == ""
+	}
+	return path != "" && !strings.Contains(path, "/")
+}
+
+func (t *TreeMux) redirectRoot(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	target := t.rootRedirect.target
+	if t.path != "" && strings.HasPrefix(target, "/") {
+		target = "/" + strings.Trim(req.Path, "/") + target
+	}
+	return LambdaRedirect(ctx, req, target, t.rootRedirect.code)
+}
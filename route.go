@@ -0,0 +1,171 @@
+package lambdarouter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Route is returned by the registration methods of Group and TreeMux and allows
+// chaining options which only apply to that method and pattern. The return value
+// can simply be ignored when no option is needed.
+//
+//	router.POST("/upload", uploadHandler).MaxBody(10 << 20)
+type Route struct {
+	method string
+	path   string
+	group  *Group
+
+	maxBody        int64
+	schema         *jsonSchema
+	timeout        time.Duration
+	cacheControl   string
+	redirectStatus int
+	streamBody     bool
+	tags           []string
+
+	// handler is the handler given at registration, and wrapped is handler with the
+	// middleware of the route applied. See With.
+	handler    HandlerFunc
+	middleware []func(HandlerFunc) HandlerFunc
+	wrapped    HandlerFunc
+
+	// nodes are the nodes the route was added to, and headers the name and value
+	// of the headers requests must have. See Header.
+	nodes   []*node
+	headers [][2]string
+}
+
+// MaxBody overrides TreeMux.MaxRequestBytes for this route. Requests with a body
+// larger than n bytes are answered with 413 Request Entity Too Large.
+func (r *Route) MaxBody(n int64) *Route {
+	r.maxBody = n
+	return r
+}
+
+// Schema validates the JSON body of every request to this route against schema
+// before calling the handler. A body which is not JSON is answered with 400 Bad
+// Request, and one which does not match the schema with 422 Unprocessable Entity
+// listing the offending values. The schema is compiled once, and registering an
+// invalid schema panics.
+//
+// Only a subset of JSON Schema is supported: type, required, properties, items,
+// enum, minLength, maxLength, minimum and maximum.
+func (r *Route) Schema(schema string) *Route {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid schema for %s %s: %s", r.method, r.path, err))
+	}
+	r.schema = compiled
+	return r
+}
+
+// Timeout overrides the global timeout set with SetGlobalTimeout for this route.
+// When d expires before the handler returns, the router answers with 504 Gateway
+// Timeout.
+func (r *Route) Timeout(d time.Duration) *Route {
+	r.timeout = d
+	return r
+}
+
+// CacheControl sets the Cache-Control header of the successful responses of this
+// route, unless the handler already set one.
+//
+//	router.GET("/logo", logoHandler).CacheControl("public, max-age=86400")
+func (r *Route) CacheControl(value string) *Route {
+	r.cacheControl = value
+	return r
+}
+
+// Name names the route so that URLFor can build its URLs. Names are unique within a
+// router, and reusing one panics.
+func (r *Route) Name(name string) *Route {
+	t := r.group.mux
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	names := t.routeNames()
+	if existing, ok := (*names)[name]; ok && existing != r {
+		panic(fmt.Sprintf("Route name %s is already used by %s %s", name, existing.method, existing.path))
+	}
+	if *names == nil {
+		*names = make(map[string]*Route)
+	}
+	(*names)[name] = r
+	return r
+}
+
+// With adds middleware to this route only. It runs after the middleware of the
+// group, outermost-first in the order it was added, so a sensitive endpoint can get
+// an extra check:
+//
+//	api.Use(logging)
+//	api.DELETE("/users/:id", deleteUser).With(audit)
+//
+// A request to DELETE /users/5 goes through logging, then audit, then reaches
+// deleteUser, while the other routes of api only go through logging.
+//
+// Like adding routes, calling With while serving requests requires
+// SafeAddRoutesWhileRunning.
+func (r *Route) With(mw ...func(HandlerFunc) HandlerFunc) *Route {
+	t := r.group.mux
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	r.middleware = append(r.middleware, mw...)
+	wrapped := r.handler
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		wrapped = r.middleware[i](wrapped)
+	}
+	r.wrapped = wrapped
+	return r
+}
+
+// serve calls the handler of the route through its own middleware. The group
+// middleware wraps it when the route is registered.
+func (r *Route) serve(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	t := r.group.mux
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+	}
+	wrapped := r.wrapped
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RUnlock()
+	}
+	return wrapped(ctx, req)
+}
+
+func (r *Route) setCacheControl(res *events.APIGatewayProxyResponse) {
+	if r.cacheControl == "" || res.StatusCode < 200 || res.StatusCode >= 400 {
+		return
+	}
+	if _, ok := headerValue(res.Headers, "Cache-Control"); ok {
+		return
+	}
+	if res.Headers == nil {
+		res.Headers = map[string]string{}
+	}
+	res.Headers["Cache-Control"] = r.cacheControl
+}
+
+// RedirectStatus sets the status of the redirects to the canonical path of this
+// route, such as the trailing slash ones, overriding TreeMux.RedirectBehavior and
+// TreeMux.RedirectMethodBehavior. A legacy alias may need a 302 Found, for example.
+func (r *Route) RedirectStatus(code int) *Route {
+	if code < 300 || code > 399 {
+		panic(fmt.Sprintf("Invalid redirect status %d for %s %s", code, r.method, r.path))
+	}
+	r.redirectStatus = code
+	return r
+}
+
+// StreamBody leaves the body of the requests to this route unread when serving
+// locally, so that the handler can stream it with BodyReader or MultipartReader
+// instead of receiving it all at once in the Body of the request. On Lambda, the
+// body is always in memory and this has no effect.
+func (r *Route) StreamBody() *Route {
+	r.streamBody = true
+	return r
+}
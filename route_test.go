@@ -0,0 +1,128 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRouteOptions(t *testing.T) {
+	slow := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+	cached := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		res := events.APIGatewayProxyResponse{StatusCode: 200, Headers: map[string]string{}}
+		if req.QueryStringParameters["own"] != "" {
+			res.Headers["cache-control"] = "no-store"
+		}
+		if req.QueryStringParameters["fail"] != "" {
+			res.StatusCode = 500
+		}
+		return res, nil
+	}
+
+	router := New()
+	router.GET("/slow", slow).Timeout(10 * time.Millisecond).CacheControl("max-age=60")
+	router.GET("/cached", cached).CacheControl("public, max-age=60").MaxBody(16)
+	// The return value can still be ignored.
+	router.GET("/plain", cached)
+
+	tests := []struct {
+		path         string
+		code         int
+		cacheControl string
+	}{
+		{"/slow", http.StatusGatewayTimeout, ""},
+		{"/cached", 200, "public, max-age=60"},
+		{"/cached?own=1", 200, "no-store"},
+		{"/cached?fail=1", 500, ""},
+		{"/plain", 200, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/__stage__"+test.path, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s expected status %d, saw %d", test.path, test.code, w.Code)
+		}
+		if got := w.Header().Get("Cache-Control"); got != test.cacheControl {
+			t.Errorf("%s expected Cache-Control %q, saw %q", test.path, test.cacheControl, got)
+		}
+	}
+}
+
+func TestRouteRedirectStatus(t *testing.T) {
+	router := New()
+	router.GET("/legacy/", simpleHandler).RedirectStatus(http.StatusFound)
+	router.GET("/current/", simpleHandler)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/legacy", http.StatusFound},
+		{"/current", http.StatusMovedPermanently},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/__stage__"+test.path, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s expected status %d, saw %d", test.path, test.code, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != "/__stage__"+test.path+"/" {
+			t.Errorf("%s expected a redirect to %s/, saw %q", test.path, test.path, location)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a status which is not a redirect")
+		}
+	}()
+	router.GET("/other", simpleHandler).RedirectStatus(http.StatusOK)
+}
+
+func TestRouteWith(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(HandlerFunc) HandlerFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name)
+				return next(ctx, req)
+			}
+		}
+	}
+
+	router := New()
+	api := router.NewGroup("/api").Use(middleware("group"))
+	api.GET("/users", simpleHandler)
+	api.DELETE("/users/:id", simpleHandler).With(middleware("audit"), middleware("confirm")).With(middleware("last"))
+
+	serve := func(method, path string) []string {
+		calls = nil
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(method, "/__stage__"+path, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s %s expected status 204, saw %d", method, path, w.Code)
+		}
+		return calls
+	}
+
+	if expected, got := []string{"group"}, serve("GET", "/api/users"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, saw %v", expected, got)
+	}
+	if expected, got := []string{"group", "audit", "confirm", "last"}, serve("DELETE", "/api/users/5"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, saw %v", expected, got)
+	}
+}
@@ -6,12 +6,15 @@ package lambdarouter
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"runtime/debug"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -21,6 +24,10 @@ import (
 type HandlerFunc func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 type PanicHandler func(http.ResponseWriter, *http.Request, interface{})
 
+// ServeLambdaPanicHandler turns a panic recovered while ServeLambda served req into
+// the response of the request. stack is the stack trace of the panic.
+type ServeLambdaPanicHandler func(ctx context.Context, req events.APIGatewayProxyRequest, err interface{}, stack []byte) (events.APIGatewayProxyResponse, error)
+
 // RedirectBehavior sets the behavior when the router redirects the request to the
 // canonical version of the requested URL using RedirectTrailingSlash or RedirectClean.
 // The default behavior is to return a 301 status, redirecting the browser to the version
@@ -31,11 +38,11 @@ type PanicHandler func(http.ResponseWriter, *http.Request, interface{})
 // and avoid this behavior, you may use Redirect307, which causes most browsers to
 // resubmit the request using the original method and request body.
 //
-// Since 307 is supposed to be a temporary redirect, the new 308 status code has been
-// proposed, which is treated the same, except it indicates correctly that the redirection
-// is permanent. The big caveat here is that the RFC is relatively recent, and older
-// browsers will not know what to do with it. Therefore its use is not recommended
-// unless you really know what you're doing.
+// Since 307 is supposed to be a temporary redirect, Redirect308 answers with the 308
+// Permanent Redirect status of RFC 7538 instead, which is treated the same, except it
+// indicates correctly that the redirection is permanent. Clients must repeat the request
+// with the same method and body, so it is the right choice for permanent redirects of
+// non-GET requests. Very old clients which predate the RFC may not know what to do with it.
 //
 // Finally, the UseHandler value will simply call the handler function for the pattern.
 type RedirectBehavior int
@@ -52,6 +59,31 @@ const (
 	URLPath                      // Use r.URL.Path
 )
 
+// Outcome classifies the result of a lookup.
+type Outcome int
+
+const (
+	Matched          Outcome = iota // A handler serves the request
+	NotFound                        // No route matches the path
+	MethodNotAllowed                // A route matches the path, but not the method
+	Redirect                        // The request is redirected to a cleaner path
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Matched:
+		return "Matched"
+	case NotFound:
+		return "NotFound"
+	case MethodNotAllowed:
+		return "MethodNotAllowed"
+	case Redirect:
+		return "Redirect"
+	default:
+		return "Unknown"
+	}
+}
+
 // LookupResult contains information about a route lookup, which is returned from Lookup and
 // can be passed to ServeLookupResult if the request should be served.
 type LookupResult struct {
@@ -59,23 +91,162 @@ type LookupResult struct {
 	// This will generally be `http.StatusNotFound` or `http.StatusMethodNotAllowed` for an
 	// error case. On a normal success, the statusCode will be `http.StatusOK`. A redirect code
 	// will also be used in the case
-	StatusCode  int
-	handler     HandlerFunc
-	params      map[string]string
-	leafHandler map[string]HandlerFunc // Only has a value when StatusCode is MethodNotAllowed.
+	StatusCode int
+	// Outcome tells matches, redirects and failures apart without looking at StatusCode.
+	Outcome Outcome
+	// MatchedPattern is the pattern of the matched route, such as /hello/:name, without
+	// the local stage, so that metrics can be tagged by route rather than by URL. It is
+	// also set when StatusCode is MethodNotAllowed, and empty otherwise.
+	MatchedPattern string
+	handler        HandlerFunc
+	params         paramList
+	leafHandler    map[string]HandlerFunc // Only has a value when StatusCode is MethodNotAllowed.
+	allow          string                 // The Allow header when StatusCode is MethodNotAllowed.
+	route          *Route
+	// routeDuration is the time the lookup took, when Server-Timing is enabled.
+	routeDuration time.Duration
 }
 
+// DefaultMaxParams is the value of TreeMux.MaxParams for a router returned by New.
+const DefaultMaxParams = 32
+
+// stageParam is the name of the wildcard that New prepends to every route when
+// the router is not running on Lambda, so that the local server can capture the stage.
+const stageParam = "__stage__"
+
 // Dump returns a text representation of the routing tree.
 func (t *TreeMux) Dump() string {
 	return t.root.dumpTree("", "")
 }
 
+// MethodsFor returns the sorted list of methods registered on the route matching
+// path. Path parameters are resolved as they would be for a request, so
+// `/items/5` reports the methods of `/items/:id`. When running locally the path
+// is given without its stage. An empty slice is returned for unmatched paths.
+func (t *TreeMux) MethodsFor(path string) []string {
+	if len(path) == 0 || path[0] != '/' {
+		return []string{}
+	}
+
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+
+	path = t.routerPath(path)
+	n, _, _ := t.root.search("", path[1:])
+	if n == nil {
+		return []string{}
+	}
+	return sortedMethods(n.leafHandler)
+}
+
+// DryRun looks up the route which would serve a request for method and path,
+// without calling any handler. It returns the pattern of the matched route and the
+// status code of the lookup: 200 on a match, 404 or 405 on failure, or the redirect
+// code when the request would be redirected. When running locally the path is
+// given without its stage.
+func (t *TreeMux) DryRun(method, path string) (pattern string, status int) {
+	if len(path) == 0 || path[0] != '/' {
+		return "", http.StatusNotFound
+	}
+
+	lr, _ := t.Lookup(events.APIGatewayProxyRequest{HTTPMethod: method, Path: t.routerPath(path)})
+	return lr.MatchedPattern, lr.StatusCode
+}
+
+// routerPath turns a path as seen by clients into the path routed by the tree,
+// which starts with a stage when running locally.
+func (t *TreeMux) routerPath(path string) string {
+	if t.path != "" {
+		return "/" + stageParam + path
+	}
+	return path
+}
+
+// requestPublicPath returns the path of req without the local stage segment.
+func (t *TreeMux) requestPublicPath(req events.APIGatewayProxyRequest) string {
+	stage := req.RequestContext.Stage
+	if t.path == "" || stage == "" {
+		return req.Path
+	}
+	path := strings.TrimPrefix(req.Path, "/"+stage)
+	if path == req.Path || path != "" && path[0] != '/' {
+		return req.Path
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// publicPath strips the local stage from a path or pattern.
+func (t *TreeMux) publicPath(path string) string {
+	if t.path == "" || !strings.HasPrefix(path, t.path) {
+		return path
+	}
+	path = path[len(t.path):]
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func sortedMethods(handlers map[string]HandlerFunc) []string {
+	methods := make([]string, 0, len(handlers))
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 func (t *TreeMux) serveHTTPPanic(w http.ResponseWriter, r *http.Request) {
 	if err := recover(); err != nil {
 		t.PanicHandler(w, r, err)
 	}
 }
 
+// routeRedirectStatusCode is like redirectStatusCode, but lets the route of n
+// override the status with Route.RedirectStatus.
+func (t *TreeMux) routeRedirectStatusCode(n *node, method string) (int, bool) {
+	if route := n.leafRoute[method]; route != nil && route.redirectStatus != 0 {
+		return route.redirectStatus, true
+	}
+	return t.redirectStatusCode(method)
+}
+
+// SetRedirectMethodBehavior overrides TreeMux.RedirectBehavior for the requests of
+// method, for example to keep the body of POST requests with a 307:
+//
+//	router.SetRedirectMethodBehavior("POST", lambdarouter.Redirect307)
+//
+// It panics when method is not a standard HTTP method, so that typos are not
+// silently ignored.
+func (t *TreeMux) SetRedirectMethodBehavior(method string, behavior RedirectBehavior) {
+	checkRedirectMethod(method)
+	if t.RedirectMethodBehavior == nil {
+		t.RedirectMethodBehavior = make(map[string]RedirectBehavior)
+	}
+	t.RedirectMethodBehavior[method] = behavior
+}
+
+// ClearRedirectMethodBehavior removes the override set for method with
+// SetRedirectMethodBehavior, so that it gets TreeMux.RedirectBehavior again.
+func (t *TreeMux) ClearRedirectMethodBehavior(method string) {
+	checkRedirectMethod(method)
+	delete(t.RedirectMethodBehavior, method)
+}
+
+func checkRedirectMethod(method string) {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+	default:
+		panic(fmt.Sprintf("Unknown HTTP method %q for a redirect behavior", method))
+	}
+}
+
 func (t *TreeMux) redirectStatusCode(method string) (int, bool) {
 	var behavior RedirectBehavior
 	var ok bool
@@ -88,9 +259,7 @@ func (t *TreeMux) redirectStatusCode(method string) (int, bool) {
 	case Redirect307:
 		return http.StatusTemporaryRedirect, true
 	case Redirect308:
-		// Go doesn't have a constant for this yet. Yet another sign
-		// that you probably shouldn't use it.
-		return 308, true
+		return http.StatusPermanentRedirect, true
 	case UseHandler:
 		return 0, false
 	default:
@@ -104,6 +273,19 @@ func redirectHandler(newPath string, statusCode int) HandlerFunc {
 	}
 }
 
+// optionsHandler answers OPTIONS requests with the methods of allow, the Allow
+// value of a node, and OPTIONS itself.
+func optionsHandler(allow string) HandlerFunc {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNoContent,
+			Headers: map[string]string{
+				"Allow": strings.Replace(allow, " ", ", ", -1) + ", OPTIONS",
+			},
+		}, nil
+	}
+}
+
 func redirect(w http.ResponseWriter, r *http.Request, newPath string, statusCode int) {
 	newURL := url.URL{
 		Path:     newPath,
@@ -115,8 +297,15 @@ func redirect(w http.ResponseWriter, r *http.Request, newPath string, statusCode
 
 func (t *TreeMux) lookup(request events.APIGatewayProxyRequest) (result LookupResult, found bool) {
 	result.StatusCode = http.StatusNotFound
+	result.Outcome = NotFound
 	path := request.Path
-	unescapedPath := request.Path
+	if path == "" {
+		// Some custom integrations send no path at all.
+		path = "/"
+	} else if path[0] != '/' {
+		return
+	}
+	unescapedPath := path
 	pathLen := len(path)
 	methode := request.HTTPMethod
 
@@ -127,6 +316,15 @@ func (t *TreeMux) lookup(request events.APIGatewayProxyRequest) (result LookupRe
 	}
 
 	n, handler, params := t.root.search(methode, path[1:])
+	if n == nil && trailingSlash && t.RedirectTrailingSlash {
+		// The slash may be all there is before a catch-all with an empty value, as
+		// in /static/ for /static/*path.
+		if n, handler, params = t.root.search(methode, path[1:]+"/"); n != nil && n.isCatchAll {
+			trailingSlash = false
+		} else {
+			n, handler, params = nil, nil, nil
+		}
+	}
 	if n == nil {
 		if t.RedirectCleanPath {
 			// Path was not found. Try cleaning it up and search again.
@@ -137,9 +335,9 @@ func (t *TreeMux) lookup(request events.APIGatewayProxyRequest) (result LookupRe
 				// Still nothing found.
 				return
 			}
-			if statusCode, ok := t.redirectStatusCode(methode); ok {
+			if statusCode, ok := t.routeRedirectStatusCode(n, methode); ok {
 				// Redirect to the actual path
-				return LookupResult{statusCode, redirectHandler(cleanPath, statusCode), nil, nil}, true
+				return LookupResult{StatusCode: statusCode, Outcome: Redirect, handler: redirectHandler(cleanPath, statusCode)}, true
 			}
 		} else {
 			// Not found.
@@ -147,21 +345,44 @@ func (t *TreeMux) lookup(request events.APIGatewayProxyRequest) (result LookupRe
 		}
 	}
 
+	route := n.leafRoute[methode]
+	if hr, h := n.matchHeaderRoute(methode, request, t.routeDisabled); h != nil {
+		route, handler = hr, h
+	} else if handler == nil && len(n.headerRoutes[methode]) != 0 {
+		// Only routes constrained by headers the request does not have.
+		return
+	}
+
+	if handler != nil && t.routeDisabled(route) {
+		// The route is hidden while one of its tags is disabled.
+		handler = nil
+	}
+
 	if handler == nil {
+		allow := t.allowedMethods(n)
+		if allow == "" {
+			// Every route of the node is hidden by a disabled tag.
+			return
+		}
 		if methode == "OPTIONS" && t.OptionsHandler != nil {
 			handler = t.OptionsHandler
+		} else if methode == "OPTIONS" && t.HandleOPTIONS {
+			handler = optionsHandler(allow)
 		}
 
 		if handler == nil {
 			result.leafHandler = n.leafHandler
+			result.allow = allow
+			result.MatchedPattern = n.pattern
 			result.StatusCode = http.StatusMethodNotAllowed
+			result.Outcome = MethodNotAllowed
 			return
 		}
 	}
 
 	if !n.isCatchAll || t.RemoveCatchAllTrailingSlash {
 		if trailingSlash != n.addSlash && t.RedirectTrailingSlash {
-			if statusCode, ok := t.redirectStatusCode(methode); ok {
+			if statusCode, ok := t.routeRedirectStatusCode(n, methode); ok {
 				var h HandlerFunc
 				if n.addSlash {
 					// Need to add a slash.
@@ -173,13 +394,13 @@ func (t *TreeMux) lookup(request events.APIGatewayProxyRequest) (result LookupRe
 				}
 
 				if h != nil {
-					return LookupResult{statusCode, h, nil, nil}, true
+					return LookupResult{StatusCode: statusCode, Outcome: Redirect, handler: h}, true
 				}
 			}
 		}
 	}
 
-	var paramMap map[string]string
+	var paramMap paramList
 	if len(params) != 0 {
 		if len(params) != len(n.leafWildcardNames) {
 			// Need better behavior here. Should this be a panic?
@@ -187,14 +408,20 @@ func (t *TreeMux) lookup(request events.APIGatewayProxyRequest) (result LookupRe
 				params, n.leafWildcardNames))
 		}
 
-		paramMap = make(map[string]string)
+		if n.isCatchAll && trailingSlash && t.RedirectTrailingSlash {
+			// The catch-all gets back the slash removed for the search.
+			params[0] += "/"
+		}
+
 		numParams := len(params)
+		paramMap = newParamList(numParams)
 		for index := 0; index < numParams; index++ {
-			paramMap[n.leafWildcardNames[numParams-index-1]] = params[index]
+			name := n.leafWildcardNames[numParams-index-1]
+			paramMap.set(name, t.transformParam(name, params[index]))
 		}
 	}
 
-	return LookupResult{http.StatusOK, handler, paramMap, nil}, true
+	return LookupResult{StatusCode: http.StatusOK, handler: handler, params: paramMap, route: route, MatchedPattern: n.pattern}, true
 }
 
 // Lookup performs a lookup without actually serving the request or mutating the request or response.
@@ -211,7 +438,7 @@ func (t *TreeMux) Lookup(request events.APIGatewayProxyRequest) (LookupResult, b
 		t.mutex.RLock()
 	}
 
-	result, found := t.lookup(request)
+	result, found := t.timedLookup(request)
 
 	if t.SafeAddRoutesWhileRunning {
 		t.mutex.RUnlock()
@@ -222,78 +449,256 @@ func (t *TreeMux) Lookup(request events.APIGatewayProxyRequest) (LookupResult, b
 
 // ServeLookupResult serves a request, given a lookup result from the Lookup function.
 func (t *TreeMux) ServeLookupResult(ctx context.Context, req events.APIGatewayProxyRequest, lr LookupResult) (events.APIGatewayProxyResponse, error) {
+	if !t.serverTiming && t.observer == nil {
+		res, err := t.serveLookupResult(ctx, req, lr)
+		t.setResponseHeaders(req, lr, &res)
+		return res, err
+	}
+
+	start := time.Now()
+	res, err := t.serveLookupResult(ctx, req, lr)
+	if t.serverTiming {
+		setServerTiming(&res, lr.routeDuration, time.Since(start))
+	}
+	t.setResponseHeaders(req, lr, &res)
+	if t.observer != nil {
+		t.observe(ctx, req, lr, res, time.Since(start))
+	}
+	return res, err
+}
+
+// setResponseHeaders adds the headers the router manages to the response of a handler.
+func (t *TreeMux) setResponseHeaders(req events.APIGatewayProxyRequest, lr LookupResult, res *events.APIGatewayProxyResponse) {
+	if t.cors != nil && lr.handler != nil {
+		t.setCORSHeaders(req, res)
+	}
+	if t.matchedRouteHeader && lr.handler != nil && lr.MatchedPattern != "" {
+		setMatchedRouteHeader(res, lr.MatchedPattern)
+	}
+	t.echoCorrelationHeaders(req, res)
+}
+
+func (t *TreeMux) serveLookupResult(ctx context.Context, req events.APIGatewayProxyRequest, lr LookupResult) (events.APIGatewayProxyResponse, error) {
+	if retryAfter, ok := t.inMaintenance(lr.MatchedPattern); ok {
+		return serviceUnavailable(ctx, req, retryAfter)
+	}
 	if lr.handler == nil {
 		if lr.StatusCode == http.StatusMethodNotAllowed && lr.leafHandler != nil {
-			if t.SafeAddRoutesWhileRunning {
-				t.mutex.RLock()
-				defer t.mutex.RUnlock()
-			}
-			allow := []string{}
-			for i := range lr.leafHandler {
-				allow = append(allow, i)
-			}
-			sort.Strings(allow)
-			return t.MethodNotAllowedHandler(ctx, req, strings.Join(allow, " "))
+			return t.MethodNotAllowedHandler(ctx, req, lr.allow)
+		} else if t.rootRedirect != nil && t.isRootPath(req.Path) {
+			return t.redirectRoot(ctx, req)
+		} else if handler, ok := t.notFoundHandlers[req.HTTPMethod]; ok {
+			return handler(ctx, req)
 		} else {
 			return t.NotFoundHandler(ctx, req)
 		}
 	} else {
-		// r = t.setDefaultRequestContext(r)
-		return lr.handler(ctx, req)
+		if t.coverage != nil && lr.route != nil {
+			t.coverage.hit(lr.route)
+		}
+		if limiter := t.rateLimiterFor(req.RequestContext.Stage); limiter != nil {
+			if state, ok := limiter.allow(); !ok {
+				return rateLimited(ctx, req, state)
+			}
+		}
+		if limit := t.bodyLimit(lr); limit > 0 && bodySize(req) > limit {
+			return LambdaRequestTooLarge(ctx, req)
+		}
+		if lr.route != nil && lr.route.schema != nil {
+			if res := validateBody(req, lr.route.schema); res != nil {
+				return *res, nil
+			}
+		}
+		ctx = context.WithValue(ctx, publicPathContextKey, t.requestPublicPath(req))
+		if lr.route != nil {
+			req.Path = lr.route.group.rewritePath(req.Path)
+		}
+		ctx = AddParamsToContext(ctx, req.PathParameters)
+		ctx = context.WithValue(ctx, jsonBodyContextKey, &jsonBody{req: req})
+		if lr.route != nil {
+			ctx = context.WithValue(ctx, groupContextKey, lr.route.group)
+		}
+		res, err := t.callHandler(ctx, req, lr.handler, t.handlerTimeout(lr))
+		if httpErr, ok := asHTTPError(err); ok {
+			res, err = httpErr.response(), nil
+		}
+		if lr.route != nil {
+			lr.route.setCacheControl(&res)
+		}
+		return res, err
 	}
 }
 
+// bodyLimit returns the maximum body size allowed for the route of lr, or 0 when
+// the size is not limited.
+func (t *TreeMux) bodyLimit(lr LookupResult) int64 {
+	if lr.route != nil && lr.route.maxBody != 0 {
+		return lr.route.maxBody
+	}
+	return t.MaxRequestBytes
+}
+
+// bodySize returns the size of the body of req once decoded.
+func bodySize(req events.APIGatewayProxyRequest) int64 {
+	if req.IsBase64Encoded {
+		padding := len(req.Body) - len(strings.TrimRight(req.Body, "="))
+		return int64(base64.StdEncoding.DecodedLen(len(req.Body)) - padding)
+	}
+	return int64(len(req.Body))
+}
+
 func (t *TreeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if t.PanicHandler != nil {
 		defer t.serveHTTPPanic(w, r)
 	}
+	if err := validateRequest(r); err != nil {
+		if t.BadRequestHandler != nil {
+			t.BadRequestHandler(w, r, err)
+		} else {
+			SimpleBadRequestHandler(w, r, err)
+		}
+		return
+	}
 
+	ctx := context.WithValue(t.withDefaultContext(r.Context()), rawQueryContextKey, r.URL.RawQuery)
+	event := newLambdaRequest(r, requestOptions{
+		forwardedHeader: t.forwardedHeader(),
+		forwardedFormat: t.ForwardedFormat,
+		queryMode:       t.QueryParamMode,
+	})
+	ctx = withTraceID(ctx, event)
+	if res, ok := t.canonicalRedirect(ctx, event); ok {
+		ResToHttp(w, r, res)
+		return
+	}
 	if t.SafeAddRoutesWhileRunning {
 		// In concurrency safe mode, we acquire a read lock on the mutex for any access.
 		// This is optional to avoid potential performance loss in high-usage scenarios.
 		t.mutex.RLock()
 	}
-	event, _ := RequestToLambda(r)
 
-	result, _ := t.lookup(event)
-	event.RequestContext.Stage = result.params["__stage__"]
-	event.StageVariables = t.StageVariables[result.params["__stage__"]]
-	delete(result.params, "__stage__")
-	event.PathParameters = result.params
+	result, _ := t.timedLookup(event)
+	event.RequestContext.Stage, _ = result.params.get(stageParam)
+	event.StageVariables = t.StageVariables.lookup(event.RequestContext.Stage, t.defaultStage)
+	event.PathParameters = result.params.toMap(stageParam)
+	if result.MatchedPattern != "" {
+		// Set like API Gateway does, for the authorizer and the handler.
+		event.Resource = resourcePath(result.MatchedPattern)
+		event.RequestContext.ResourcePath = event.Resource
+		event.RequestContext.ResourceID = localResourceID(event.Resource)
+	}
 	if t.SafeAddRoutesWhileRunning {
 		t.mutex.RUnlock()
 	}
-	if t.authorizer != nil {
-		res, err := t.authorizer(context.Background(), GenerateLambdaAuthorizer(event))
+	if r.Body != nil {
+		defer drainBody(r.Body)
+		if result.route != nil && result.route.streamBody {
+			body := &guardedBody{body: limitBody(r.Body, t.bodyLimit(result))}
+			// Deferred after drainBody, so that it runs first.
+			defer body.stop()
+			ctx = context.WithValue(ctx, bodyReaderContextKey, body)
+		} else {
+			event.Body = readBody(r.Body, t.bodyLimit(result))
+			encodeBody(&event)
+		}
+	}
+	if t.authorizer != nil && (event.HTTPMethod != "OPTIONS" || t.AuthorizeOptions) {
+		res, err := t.authorize(ctx, event)
 		if err != nil {
-			fmt.Printf("%s\n", err.Error())
+			fmt.Printf("authorizer: %s\n", err.Error())
+			if t.authorizerFailureMode == FailClosed {
+				responce, _ := LambdaForbidden(ctx, event)
+				ResToHttp(w, r, responce)
+				return
+			}
+		} else if !policyAllows(res.PolicyDocument, GenerateArn(event)) {
+			responce, _ := LambdaForbidden(ctx, event)
+			ResToHttp(w, r, responce)
+			return
 		}
 		event.RequestContext.Authorizer = res.Context
 	}
-	responce, _ := t.ServeLookupResult(context.Background(), event, result)
+	if !t.acquireSlot() {
+		responce, _ := LambdaTooManyRequests(ctx, event)
+		ResToHttp(w, r, responce)
+		return
+	}
+	defer t.releaseSlot()
+	responce, _ := t.ServeLookupResult(ctx, event, result)
 	ResToHttp(w, r, responce)
 }
 
-func (t *TreeMux) ServeLambda(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// if t.PanicHandler != nil {
-	// 	defer t.serveHTTPPanic(w, r)
-	// }
-	req.Path = CleanPath(req)
+func (t *TreeMux) ServeLambda(ctx context.Context, req events.APIGatewayProxyRequest) (res events.APIGatewayProxyResponse, err error) {
+	var cold bool
+	if t.coldStartHeader {
+		// Deferred first so that it also flags the response of the panic handler.
+		defer func() {
+			if cold {
+				setColdStartHeader(&res)
+			}
+		}()
+	}
+	if t.ServeLambdaPanicHandler != nil {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				res, err = t.ServeLambdaPanicHandler(ctx, req, recovered, debug.Stack())
+			}
+		}()
+	}
+	ctx, cold = markColdStart(withTraceID(t.withDefaultContext(ctx), req))
+	if isProxyResource(req.Resource) {
+		req.Path = UseTemplate(req)
+	}
+	if res, ok := t.canonicalRedirect(ctx, req); ok {
+		return res, nil
+	}
 	if t.SafeAddRoutesWhileRunning {
 		// In concurrency safe mode, we acquire a read lock on the mutex for any access.
 		// This is optional to avoid potential performance loss in high-usage scenarios.
 		t.mutex.RLock()
 	}
 
-	result, _ := t.lookup(req)
-	if strings.Contains(req.Resource, "{proxy+}") {
-		req.PathParameters = result.params
+	result, _ := t.timedLookup(req)
+	req.PathParameters = mergeParams(result.params, req.PathParameters)
+	if result.MatchedPattern != "" && (isProxyResource(req.Resource) || req.RequestContext.ResourcePath == "") {
+		req.RequestContext.ResourcePath = resourcePath(result.MatchedPattern)
 	}
 	if t.SafeAddRoutesWhileRunning {
 		t.mutex.RUnlock()
 	}
 
-	return t.ServeLookupResult(ctx, req, result)
+	res, err = t.ServeLookupResult(ctx, req, result)
+	encodeBinaryBody(&res)
+	return res, err
+}
+
+// isProxyResource reports whether resource has a greedy path variable, such as
+// {proxy+}. The path of the request is then rebuilt from the resource, which drops
+// the base path of custom domains. Other resources are explicit, and their requests
+// are routed on the path as is.
+func isProxyResource(resource string) bool {
+	return strings.Contains(resource, "+}")
+}
+
+// mergeParams combines the parameters computed by the router with the ones provided
+// by API Gateway. The values from API Gateway win, so that explicitly defined
+// resources behave as configured, while the router fills in the parameters API
+// Gateway can not know about, such as the ones hidden behind a {proxy+} resource.
+func mergeParams(computed paramList, provided map[string]string) map[string]string {
+	if len(provided) == 0 {
+		return computed.toMap("")
+	}
+	if computed.len() == 0 {
+		return provided
+	}
+
+	merged := computed.toMap("")
+	for key, value := range provided {
+		if value != "" {
+			merged[key] = value
+		}
+	}
+	return merged
 }
 
 // MethodNotAllowedHandler is the default handler for TreeMux.MethodNotAllowedHandler,
@@ -314,28 +719,74 @@ func New() *TreeMux {
 	tm := &TreeMux{
 		root:                    &node{path: "/"},
 		NotFoundHandler:         LambdaNotFound,
+		LambdaPanicHandler:      SimpleLambdaPanicHandler,
+		ServeLambdaPanicHandler: SimpleServeLambdaPanicHandler,
 		MethodNotAllowedHandler: LambdaNotAllowed,
 		HeadCanUseGet:           true,
 		RedirectTrailingSlash:   true,
 		RedirectCleanPath:       true,
 		RedirectBehavior:        Redirect301,
 		RedirectMethodBehavior:  make(map[string]RedirectBehavior),
+		MaxParams:               DefaultMaxParams,
 		PathSource:              RequestURI,
 		EscapeAddedRoutes:       false,
+		correlationHeaders:      DefaultCorrelationHeaders,
+		warmerDetector:          IsWarmerEvent,
 	}
 	tm.Group.mux = tm
 	if len(os.Getenv("AWS_EXECUTION_ENV")) == 0 {
-		tm.Group = *tm.NewGroup("/:__stage__")
+		tm.Group = Group{path: "/:" + stageParam, mux: tm}
 	}
 	return tm
 }
 
+// StageVariables holds the stage variables passed to the handlers by the local
+// server, by stage name.
 type StageVariables map[string]map[string]string
 
+// lookup returns the variables of stage. A stage which is not listed matches one
+// differing only in case, and then falls back to defaultStage.
+func (v StageVariables) lookup(stage, defaultStage string) map[string]string {
+	if variables, ok := v[stage]; ok {
+		return variables
+	}
+	for name, variables := range v {
+		if strings.EqualFold(name, stage) {
+			return variables
+		}
+	}
+	if defaultStage != "" && defaultStage != stage {
+		return v.lookup(defaultStage, "")
+	}
+	return nil
+}
+
+// SetDefaultStage sets the stage whose StageVariables are passed to requests served
+// locally for a stage which is not listed, such as when a single deployment is tested
+// under any stage name.
+func (t *TreeMux) SetDefaultStage(name string) {
+	t.defaultStage = name
+}
+
 func (r *TreeMux) SetAuthorizer(handler func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error)) {
 	r.authorizer = handler
 }
 
+// SetNotFoundHandlerForMethod sets the handler called instead of NotFoundHandler
+// for requests with the given method which match no route, for example to answer
+// GET requests with an HTML page and the others with JSON. A nil handler restores
+// NotFoundHandler for the method.
+func (t *TreeMux) SetNotFoundHandlerForMethod(method string, handler HandlerFunc) {
+	if handler == nil {
+		delete(t.notFoundHandlers, method)
+		return
+	}
+	if t.notFoundHandlers == nil {
+		t.notFoundHandlers = make(map[string]HandlerFunc)
+	}
+	t.notFoundHandlers[method] = handler
+}
+
 func (r *TreeMux) Serve(addr string, stages StageVariables) error {
 	r.StageVariables = stages
 	if len(os.Getenv("AWS_EXECUTION_ENV")) == 0 {
@@ -345,7 +796,7 @@ func (r *TreeMux) Serve(addr string, stages StageVariables) error {
 		if os.Getenv("AUTHORIZER") == "true" {
 			lambda.Start(r.authorizer)
 		} else {
-			lambda.Start(r.ServeLambda)
+			lambda.Start(r.LambdaHandler())
 		}
 		return nil
 	}
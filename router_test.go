@@ -2,14 +2,17 @@ package lambdarouter
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -84,7 +87,7 @@ func serve(router *TreeMux, w http.ResponseWriter, r *http.Request, useLookup bo
 	if useLookup {
 		event, _ := RequestToLambda(r)
 		result, found := router.Lookup(event)
-		event.PathParameters = result.params
+		event.PathParameters = result.params.toMap("")
 		router.ServeLookupResult(context.Background(), event, result)
 		return found
 	} else {
@@ -93,6 +96,14 @@ func serve(router *TreeMux, w http.ResponseWriter, r *http.Request, useLookup bo
 	}
 }
 
+// newLambdaRouter returns a router set up as it is when running on Lambda,
+// that is without the local stage prefix.
+func newLambdaRouter() *TreeMux {
+	os.Setenv("AWS_EXECUTION_ENV", "AWS_Lambda_go1.x")
+	defer os.Unsetenv("AWS_EXECUTION_ENV")
+	return New()
+}
+
 func TestMethods(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Log(scenario.description)
@@ -183,6 +194,38 @@ func TestNotFound(t *testing.T) {
 	}
 }
 
+func TestNotFoundHandlerForMethod(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/user/abc", simpleHandler)
+	router.SetNotFoundHandlerForMethod("GET", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Headers:    map[string]string{"Content-Type": "text/html"},
+			Body:       "<h1>Not Found</h1>",
+		}, nil
+	})
+
+	serve := func(method, path string) events.APIGatewayProxyResponse {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: method, Resource: path, Path: path})
+		return res
+	}
+
+	if res := serve("GET", "/unknown"); res.StatusCode != http.StatusNotFound || res.Body != "<h1>Not Found</h1>" {
+		t.Errorf("Expected the HTML page for GET, saw %d %q", res.StatusCode, res.Body)
+	}
+	if res := serve("POST", "/unknown"); res.StatusCode != http.StatusNotFound || res.Body != `{"error": "Not Found"}` {
+		t.Errorf("Expected the global handler for POST, saw %d %q", res.StatusCode, res.Body)
+	}
+	if res := serve("GET", "/user/abc"); res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected matched routes to be served, saw %d", res.StatusCode)
+	}
+
+	router.SetNotFoundHandlerForMethod("GET", nil)
+	if res := serve("GET", "/unknown"); res.Body != `{"error": "Not Found"}` {
+		t.Errorf("Expected the global handler once the GET one is removed, saw %q", res.Body)
+	}
+}
+
 func TestMethodNotAllowedHandler(t *testing.T) {
 	calledNotAllowed := false
 
@@ -232,6 +275,49 @@ func TestMethodNotAllowedHandler(t *testing.T) {
 	}
 }
 
+func TestMethodNotAllowedAllow(t *testing.T) {
+	var allow string
+	router := New()
+	router.MethodNotAllowedHandler = func(ctx context.Context, req events.APIGatewayProxyRequest, a string) (events.APIGatewayProxyResponse, error) {
+		allow = a
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusMethodNotAllowed}, nil
+	}
+	router.PUT("/user/:id", simpleHandler)
+	router.GET("/user/:id", simpleHandler)
+
+	serve := func() {
+		r, _ := newRequest("POST", "/__stage__/user/1", nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve()
+	if allow != "GET HEAD PUT" {
+		t.Errorf("Expected the sorted methods of the route, saw %q", allow)
+	}
+
+	// The list follows the routes added later.
+	router.DELETE("/user/:id", simpleHandler)
+	serve()
+	if allow != "DELETE GET HEAD PUT" {
+		t.Errorf("Expected the new method in the list, saw %q", allow)
+	}
+}
+
+func BenchmarkMethodNotAllowed(b *testing.B) {
+	router := New()
+	router.GET("/user/:id", simpleHandler)
+	router.PUT("/user/:id", simpleHandler)
+	router.DELETE("/user/:id", simpleHandler)
+	req := events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/__stage__/user/1", Resource: "/__stage__/user/1"}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeLambda(ctx, req)
+	}
+}
+
 func TestOptionsHandler(t *testing.T) {
 	optionsHandler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		return events.APIGatewayProxyResponse{
@@ -371,7 +457,7 @@ func behaviorToCode(b RedirectBehavior) int {
 	case Redirect307:
 		return http.StatusTemporaryRedirect
 	case Redirect308:
-		return 308
+		return http.StatusPermanentRedirect
 	case UseHandler:
 		// Not normally, but the handler in the below test returns this.
 		return http.StatusNoContent
@@ -596,6 +682,59 @@ func TestCatchAllTrailingSlashRedirect(t *testing.T) {
 
 }
 
+func TestCatchAllParams(t *testing.T) {
+	var params map[string]string
+	router := newLambdaRouter()
+	router.GET("/static/*filepath", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		params = req.PathParameters
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	router.GET("/users/:id/files/*path", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		params = req.PathParameters
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	tests := []struct {
+		path     string
+		status   int
+		expected map[string]string
+	}{
+		{"/static/", 200, map[string]string{"filepath": ""}},
+		{"/static/a/b/c", 200, map[string]string{"filepath": "a/b/c"}},
+		{"/static/a/b/", 200, map[string]string{"filepath": "a/b/"}},
+		{"/static/a%20b/c%2Fd", 200, map[string]string{"filepath": "a b/c/d"}},
+		{"/users/5/files/", 200, map[string]string{"id": "5", "path": ""}},
+		{"/users/5/files/x/y", 200, map[string]string{"id": "5", "path": "x/y"}},
+		{"/static", 404, nil},
+	}
+	for _, test := range tests {
+		params = nil
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: test.path, Path: test.path})
+		if res.StatusCode != test.status || !reflect.DeepEqual(params, test.expected) {
+			t.Errorf("%s expected %d with %v, saw %d with %v", test.path, test.status, test.expected, res.StatusCode, params)
+		}
+	}
+
+	// Removing the trailing slash redirects, except for an empty value.
+	router.RemoveCatchAllTrailingSlash = true
+	res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/static/a/", Path: "/static/a/"})
+	if res.StatusCode != http.StatusMovedPermanently || res.Headers["Location"] != "/static/a" {
+		t.Errorf("Expected a redirect to /static/a, saw %d to %q", res.StatusCode, res.Headers["Location"])
+	}
+	res, _ = router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/static/", Path: "/static/"})
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected /static/ to be served, saw %d", res.StatusCode)
+	}
+
+	// Without trailing slash handling, the slash stays in the value.
+	router.RedirectTrailingSlash = false
+	params = nil
+	router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/static/a/", Path: "/static/a/"})
+	if params["filepath"] != "a/" {
+		t.Errorf("Expected a/ without trailing slash handling, saw %q", params["filepath"])
+	}
+}
+
 func TestRoot(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Log(scenario.description)
@@ -787,6 +926,832 @@ func TestPathSource(t *testing.T) {
 	}
 }
 
+func TestMethodsFor(t *testing.T) {
+	router := New()
+	router.HeadCanUseGet = false
+	router.GET("/items/:id", simpleHandler)
+	router.DELETE("/items/:id", simpleHandler)
+
+	methods := router.MethodsFor("/items/5")
+	expected := []string{"DELETE", "GET"}
+	if !reflect.DeepEqual(methods, expected) {
+		t.Errorf("Expected methods %v for /items/5, saw %v", expected, methods)
+	}
+
+	methods = router.MethodsFor("/unknown")
+	if methods == nil || len(methods) != 0 {
+		t.Errorf("Expected empty methods for /unknown, saw %v", methods)
+	}
+}
+
+func TestMaxRequestBytes(t *testing.T) {
+	router := New()
+	router.MaxRequestBytes = 8
+	router.POST("/api", simpleHandler)
+	router.POST("/upload", simpleHandler).MaxBody(64)
+
+	testBody := func(path string, size int, expectedCode int) {
+		body := strings.NewReader(strings.Repeat("a", size))
+		r, _ := newRequest("POST", "/__stage__"+path, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != expectedCode {
+			t.Errorf("%s with %d bytes expected code %d, saw %d", path, size, expectedCode, w.Code)
+		}
+	}
+
+	testBody("/api", 8, http.StatusNoContent)
+	testBody("/api", 32, http.StatusRequestEntityTooLarge)
+	testBody("/upload", 32, http.StatusNoContent)
+	testBody("/upload", 65, http.StatusRequestEntityTooLarge)
+
+	req := events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/__stage__/api", Body: strings.Repeat("a", 32)}
+	result, _ := router.Lookup(req)
+	res, _ := router.ServeLookupResult(context.Background(), req, result)
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected code 413 from ServeLookupResult, saw %d", res.StatusCode)
+	}
+}
+
+func TestDumpMethods(t *testing.T) {
+	router := New()
+	router.HeadCanUseGet = false
+	router.GET("/users/:id", simpleHandler)
+	router.DELETE("/users/:id", simpleHandler)
+	router.POST("/users", simpleHandler)
+
+	dump := router.Dump()
+	t.Log(dump)
+	if !strings.Contains(dump, ":wildcard [0] [DELETE,GET] wildcards [__stage__ id]") {
+		t.Error("Expected dump to annotate /users/:id with [DELETE,GET]")
+	}
+	if !strings.Contains(dump, "users [1] [POST] wildcards") {
+		t.Error("Expected dump to annotate /users with [POST]")
+	}
+}
+
+func TestGlobalTimeout(t *testing.T) {
+	slowHandler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		time.Sleep(500 * time.Millisecond)
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+
+	router := New()
+	router.GET("/slow", slowHandler)
+	router.GET("/fast", simpleHandler)
+	router.SetGlobalTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r, _ := newRequest("GET", "/__stage__/slow", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected slow handler to time out with %d, saw %d", http.StatusGatewayTimeout, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("Expected slow handler to be cut off at the global timeout, took %v", elapsed)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = newRequest("GET", "/__stage__/fast", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected fast handler to return %d, saw %d", http.StatusNoContent, w.Code)
+	}
+}
+
+func TestTimeoutStreamBody(t *testing.T) {
+	read := make(chan error, 1)
+	router := New()
+	router.POST("/upload", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		body := BodyReader(ctx, req)
+		buf := make([]byte, 1)
+		for {
+			if _, err := body.Read(buf); err != nil {
+				read <- err
+				return LambdaBadRequest(ctx, req, err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}).StreamBody().Timeout(20 * time.Millisecond)
+
+	body := &countingReader{r: strings.NewReader(strings.Repeat("x", 1000))}
+	w := httptest.NewRecorder()
+	r, _ := newRequest("POST", "/__stage__/upload", body)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected the slow upload to time out with %d, saw %d", http.StatusGatewayTimeout, w.Code)
+	}
+
+	select {
+	case err := <-read:
+		if err != http.ErrBodyReadAfterClose {
+			t.Errorf("Expected the reads after the timeout to fail, saw %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the handler to stop once the body was closed")
+	}
+}
+
+func TestCorrelationHeaders(t *testing.T) {
+	router := New()
+	router.GET("/abc", simpleHandler)
+
+	w := httptest.NewRecorder()
+	r, _ := newRequest("GET", "/__stage__/abc", nil)
+	r.Header.Set("X-Request-Id", "req-1")
+	router.ServeHTTP(w, r)
+	if got := w.Header().Get("X-Request-Id"); got != "req-1" {
+		t.Errorf("Expected X-Request-Id req-1 to be echoed, saw %q", got)
+	}
+
+	router.SetCorrelationHeaders([]string{"X-Custom-Id"})
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Path:       "/__stage__/abc",
+		Headers:    map[string]string{"x-custom-id": "abc", "X-Request-Id": "req-2"},
+	}
+	result, _ := router.Lookup(req)
+	res, _ := router.ServeLookupResult(context.Background(), req, result)
+	if got := res.Headers["X-Custom-Id"]; got != "abc" {
+		t.Errorf("Expected X-Custom-Id abc to be echoed, saw %q", got)
+	}
+	if _, ok := res.Headers["X-Request-Id"]; ok {
+		t.Error("Expected X-Request-Id not to be echoed once removed from the correlation headers")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	called := false
+	router := New()
+	router.GET("/users/:id", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	testDryRun := func(method, path, expectedPattern string, expectedStatus int) {
+		pattern, status := router.DryRun(method, path)
+		if pattern != expectedPattern || status != expectedStatus {
+			t.Errorf("%s %s expected pattern %q and status %d, saw %q and %d",
+				method, path, expectedPattern, expectedStatus, pattern, status)
+		}
+	}
+
+	testDryRun("GET", "/users/5", "/users/:id", http.StatusOK)
+	testDryRun("POST", "/users/5", "/users/:id", http.StatusMethodNotAllowed)
+	testDryRun("GET", "/unknown", "", http.StatusNotFound)
+
+	if called {
+		t.Error("DryRun unexpectedly called the handler")
+	}
+}
+
+func TestLookupOutcome(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/users/:id", simpleHandler)
+	router.GET("/posts/", simpleHandler)
+
+	tests := []struct {
+		method   string
+		path     string
+		expected Outcome
+	}{
+		{"GET", "/users/5", Matched},
+		{"GET", "/unknown", NotFound},
+		{"POST", "/users/5", MethodNotAllowed},
+		{"GET", "/users/5/", Redirect},
+		{"GET", "/posts", Redirect},
+		{"GET", "/users//5", Redirect},
+	}
+	for _, test := range tests {
+		result, _ := router.Lookup(events.APIGatewayProxyRequest{HTTPMethod: test.method, Path: test.path})
+		if result.Outcome != test.expected {
+			t.Errorf("%s %s expected outcome %s, saw %s", test.method, test.path, test.expected, result.Outcome)
+		}
+	}
+}
+
+func TestParamTransform(t *testing.T) {
+	var username, id string
+	router := New()
+	router.GET("/users/:username/:id", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		username = req.PathParameters["username"]
+		id = req.PathParameters["id"]
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	router.Transform("username", strings.ToLower)
+	router.Transform("username", strings.TrimSpace)
+
+	w := httptest.NewRecorder()
+	r, _ := newRequest("GET", "/__stage__/users/%20BOB%20/AbC", nil)
+	router.ServeHTTP(w, r)
+
+	if username != "bob" {
+		t.Errorf("Expected username bob, saw %q", username)
+	}
+	if id != "AbC" {
+		t.Errorf("Expected id to be left untouched as AbC, saw %q", id)
+	}
+}
+
+func TestBodyJSON(t *testing.T) {
+	var name interface{}
+	var cached bool
+	router := New()
+	router.POST("/users", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		first, err := BodyJSON(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name = first["name"]
+		first["seen"] = true
+
+		second, _ := BodyJSON(ctx)
+		cached = second["seen"] == true
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := newRequest("POST", "/__stage__/users", strings.NewReader(`{"name": "bob"}`))
+	router.ServeHTTP(w, r)
+
+	if name != "bob" {
+		t.Errorf("Expected name bob from the JSON body, saw %v", name)
+	}
+	if !cached {
+		t.Error("Expected the second BodyJSON call to return the cached map")
+	}
+
+	if _, err := BodyJSON(context.Background()); err != ErrNoRequestBody {
+		t.Errorf("Expected ErrNoRequestBody outside of a request, saw %v", err)
+	}
+}
+
+func TestServeLambdaPathParameters(t *testing.T) {
+	var params map[string]string
+	router := newLambdaRouter()
+	router.GET("/users/:id", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		params = req.PathParameters
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	// A {proxy+} resource only tells the handler about the proxy parameter.
+	proxy := events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Resource:       "/{proxy+}",
+		Path:           "/users/5",
+		PathParameters: map[string]string{"proxy": "users/5"},
+	}
+	router.ServeLambda(context.Background(), proxy)
+	expected := map[string]string{"id": "5", "proxy": "users/5"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("Proxy resource expected params %v, saw %v", expected, params)
+	}
+
+	// An explicit resource keeps the parameters provided by API Gateway.
+	explicit := events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Resource:       "/users/{id}",
+		Path:           "/users/5",
+		PathParameters: map[string]string{"id": "5"},
+	}
+	router.ServeLambda(context.Background(), explicit)
+	expected = map[string]string{"id": "5"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("Explicit resource expected params %v, saw %v", expected, params)
+	}
+}
+
+func TestServeLambdaResourcePath(t *testing.T) {
+	var path string
+	router := newLambdaRouter()
+	router.GET("/users/:id", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		path = req.Path
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	// With a proxy integration, the path is rebuilt from the resource, which drops
+	// the base path of a custom domain.
+	proxy := events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Resource:       "/{proxy+}",
+		Path:           "/api/users/5",
+		PathParameters: map[string]string{"proxy": "users/5"},
+	}
+	if res, _ := router.ServeLambda(context.Background(), proxy); res.StatusCode != 200 || path != "/users/5" {
+		t.Errorf("Proxy resource expected 200 for /users/5, saw %d for %s", res.StatusCode, path)
+	}
+
+	// An explicit resource is routed on the path as is, even without the parameters
+	// the template would need.
+	path = ""
+	explicit := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Resource:   "/users/{id}",
+		Path:       "/users/5",
+	}
+	if res, _ := router.ServeLambda(context.Background(), explicit); res.StatusCode != 200 || path != "/users/5" {
+		t.Errorf("Explicit resource expected 200 for /users/5, saw %d for %s", res.StatusCode, path)
+	}
+}
+
+func TestAuthorizerSkipsOptions(t *testing.T) {
+	var calls int
+	router := New()
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		calls++
+		return events.APIGatewayCustomAuthorizerResponse{}, nil
+	})
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+	router.GET("/user", handler)
+	router.OPTIONS("/user", handler)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("OPTIONS", "/__stage__/user", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("OPTIONS expected status 200, saw %d", w.Code)
+	}
+	if calls != 0 {
+		t.Errorf("OPTIONS expected no authorizer call, saw %d", calls)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/__stage__/user", nil)
+	router.ServeHTTP(w, r)
+	if calls != 1 {
+		t.Errorf("GET expected one authorizer call, saw %d", calls)
+	}
+
+	router.AuthorizeOptions = true
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("OPTIONS", "/__stage__/user", nil)
+	router.ServeHTTP(w, r)
+	if calls != 2 {
+		t.Errorf("OPTIONS with AuthorizeOptions expected an authorizer call, saw %d calls", calls)
+	}
+}
+
+func TestRedirect308(t *testing.T) {
+	var called bool
+	router := New()
+	router.RedirectMethodBehavior["POST"] = Redirect308
+	router.POST("/upload", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/__stage__/upload/", strings.NewReader("data"))
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusPermanentRedirect {
+		t.Errorf("Expected status %d, saw %d", http.StatusPermanentRedirect, w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "/__stage__/upload" {
+		t.Errorf("Expected a redirect to /__stage__/upload, saw %q", location)
+	}
+	if called {
+		t.Error("Expected the handler not to be called on redirect")
+	}
+}
+
+type trackedBody struct {
+	io.Reader
+	closed int
+}
+
+func (b *trackedBody) Close() error {
+	b.closed++
+	return nil
+}
+
+func TestRequestBodyDrained(t *testing.T) {
+	router := New()
+	router.MaxRequestBytes = 16
+	router.POST("/upload", simpleHandler)
+
+	for _, path := range []string{"/upload", "/missing"} {
+		body := &trackedBody{Reader: strings.NewReader(strings.Repeat("a", 1024))}
+		r, _ := http.NewRequest("POST", "/__stage__"+path, body)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		if body.closed != 1 {
+			t.Errorf("%s expected the body to be closed once, saw %d", path, body.closed)
+		}
+		if n, _ := io.Copy(io.Discard, body); n != 0 {
+			t.Errorf("%s expected the body to be drained, %d bytes were left", path, n)
+		}
+	}
+}
+
+func TestAuthorizerContext(t *testing.T) {
+	var hasDeadline bool
+	var value interface{}
+	router := New()
+	router.GET("/user", simpleHandler)
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		_, hasDeadline = ctx.Deadline()
+		value = ctx.Value("trace")
+		return events.APIGatewayCustomAuthorizerResponse{}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), "trace", "abc"), time.Minute)
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, "GET", "/__stage__/user", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	if !hasDeadline {
+		t.Error("Expected the authorizer to observe the deadline of the request context")
+	}
+	if value != "abc" {
+		t.Errorf("Expected the authorizer to read the request context values, saw %v", value)
+	}
+}
+
+func TestAuthorizerPathParameters(t *testing.T) {
+	var event events.APIGatewayCustomAuthorizerRequestTypeRequest
+	router := New()
+	router.GET("/orgs/:org/files/*path", simpleHandler)
+	router.SetAuthorizer(func(ctx context.Context, req events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+		event = req
+		return events.APIGatewayCustomAuthorizerResponse{}, nil
+	})
+
+	r, _ := http.NewRequest("GET", "/prod/orgs/acme/files/a/b.txt", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	if event.PathParameters["org"] != "acme" || event.PathParameters["path"] != "a/b.txt" {
+		t.Errorf("Expected the authorizer to see the path parameters, saw %v", event.PathParameters)
+	}
+	if _, ok := event.PathParameters[stageParam]; ok {
+		t.Error("Expected the stage to be left out of the path parameters")
+	}
+	if event.Resource != "/orgs/{org}/files/{path+}" || event.RequestContext.ResourcePath != event.Resource {
+		t.Errorf("Expected the resource of the matched route, saw %q and %q", event.Resource, event.RequestContext.ResourcePath)
+	}
+	if event.Type != "REQUEST" || event.RequestContext.Stage != "prod" {
+		t.Errorf("Expected a REQUEST event for the prod stage, saw %q for %q", event.Type, event.RequestContext.Stage)
+	}
+}
+
+func TestQueryParamMode(t *testing.T) {
+	var req events.APIGatewayProxyRequest
+	router := New()
+	router.GET("/search", func(ctx context.Context, r events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		req = r
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	tests := []struct {
+		mode     QueryParamMode
+		expected string
+	}{
+		{QueryParamFirst, "1"},
+		{QueryParamLast, "2"},
+		{QueryParamAll, "1,2"},
+	}
+	for _, test := range tests {
+		router.QueryParamMode = test.mode
+		r, _ := http.NewRequest("GET", "/__stage__/search?x=1&x=2&y=3", nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		expected := map[string]string{"x": test.expected, "y": "3"}
+		if !reflect.DeepEqual(req.QueryStringParameters, expected) {
+			t.Errorf("Mode %d expected %v, saw %v", test.mode, expected, req.QueryStringParameters)
+		}
+		all := map[string][]string{"x": {"1", "2"}, "y": {"3"}}
+		if !reflect.DeepEqual(req.MultiValueQueryStringParameters, all) {
+			t.Errorf("Mode %d expected all the values %v, saw %v", test.mode, all, req.MultiValueQueryStringParameters)
+		}
+	}
+}
+
+func TestServerTiming(t *testing.T) {
+	router := New()
+	router.GET("/user", simpleHandler)
+
+	serve := func() string {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/__stage__/user", nil)
+		router.ServeHTTP(w, r)
+		return w.Header().Get("Server-Timing")
+	}
+
+	if timing := serve(); timing != "" {
+		t.Errorf("Expected no Server-Timing header by default, saw %q", timing)
+	}
+
+	router.EnableServerTiming()
+	timing := serve()
+	var route, handler float64
+	if n, _ := fmt.Sscanf(timing, "route;dur=%f, handler;dur=%f", &route, &handler); n != 2 {
+		t.Errorf("Expected route and handler metrics, saw %q", timing)
+	}
+	if route < 0 || handler < 0 {
+		t.Errorf("Expected positive durations, saw %q", timing)
+	}
+}
+
+func TestMatchedRouteHeader(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", simpleHandler)
+	router.GET("/users/me", simpleHandler)
+
+	serve := func(path string) string {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, r)
+		return w.Header().Get("X-Matched-Route")
+	}
+
+	if pattern := serve("/prod/users/5"); pattern != "" {
+		t.Errorf("Expected no X-Matched-Route header by default, saw %q", pattern)
+	}
+
+	router.EnableMatchedRouteHeader()
+	if pattern := serve("/prod/users/5"); pattern != "/users/:id" {
+		t.Errorf("Expected /users/:id, saw %q", pattern)
+	}
+	if pattern := serve("/prod/users/me"); pattern != "/users/me" {
+		t.Errorf("Expected /users/me, saw %q", pattern)
+	}
+	if pattern := serve("/prod/missing"); pattern != "" {
+		t.Errorf("Expected no X-Matched-Route header without a match, saw %q", pattern)
+	}
+}
+
+func TestRootRedirect(t *testing.T) {
+	router := New()
+	router.GET("/docs", simpleHandler)
+	router.SetRootRedirect("/docs", http.StatusFound)
+
+	for _, path := range []string{"/prod", "/prod/"} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "/prod/docs" {
+			t.Errorf("%s expected a 302 redirect to /prod/docs, saw %d to %q", path, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/prod/missing", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected other paths to stay 404, saw %d", w.Code)
+	}
+
+	lambda := newLambdaRouter()
+	lambda.SetRootRedirect("https://example.com/docs", http.StatusMovedPermanently)
+	res, _ := lambda.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/", Path: "/"})
+	if res.StatusCode != http.StatusMovedPermanently || res.Headers["Location"] != "https://example.com/docs" {
+		t.Errorf("Expected a 301 redirect to the docs on Lambda, saw %d to %q", res.StatusCode, res.Headers["Location"])
+	}
+
+	// An explicit root handler wins.
+	router.GET("/", simpleHandler)
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/prod/", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected the root handler to be called, saw %d", w.Code)
+	}
+}
+
+func TestServeLambdaPanic(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/abc", panicHandler)
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/abc", Path: "/abc"}
+
+	var recovered interface{}
+	var stack []byte
+	router.ServeLambdaPanicHandler = func(ctx context.Context, req events.APIGatewayProxyRequest, err interface{}, s []byte) (events.APIGatewayProxyResponse, error) {
+		recovered, stack = err, s
+		return SimpleServeLambdaPanicHandler(ctx, req, err, nil)
+	}
+	res, err := router.ServeLambda(context.Background(), req)
+	if err != nil || res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 response, saw %d, %v", res.StatusCode, err)
+	}
+	if recovered != "test panic" || !strings.Contains(string(stack), "panic") {
+		t.Errorf("Expected the panic value and its stack trace, saw %v and %q", recovered, stack)
+	}
+
+	// With a timeout, the handler panics in another goroutine.
+	router.SetGlobalTimeout(time.Second)
+	if res, _ := router.ServeLambda(context.Background(), req); res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 response with a timeout, saw %d", res.StatusCode)
+	}
+
+	router.ServeLambdaPanicHandler = nil
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected the panic to propagate without a panic handler")
+		}
+	}()
+	router.ServeLambda(context.Background(), req)
+}
+
+func TestStageVariables(t *testing.T) {
+	var variables map[string]string
+	router := New()
+	router.GET("/abc", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		variables = req.StageVariables
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	router.StageVariables = StageVariables{
+		"dev":  {"table": "dev-table"},
+		"prod": {"table": "prod-table"},
+		"Prod": {"table": "exact-table"},
+	}
+
+	serve := func(stage string) string {
+		variables = nil
+		r, _ := http.NewRequest("GET", "/"+stage+"/abc", nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		return variables["table"]
+	}
+
+	if table := serve("Prod"); table != "exact-table" {
+		t.Errorf("Expected the exact match to win, saw %q", table)
+	}
+	if table := serve("DEV"); table != "dev-table" {
+		t.Errorf("Expected a match ignoring case, saw %q", table)
+	}
+	if table := serve("test"); table != "" {
+		t.Errorf("Expected no variables for an unknown stage, saw %q", table)
+	}
+
+	router.SetDefaultStage("dev")
+	if table := serve("test"); table != "dev-table" {
+		t.Errorf("Expected the default stage for an unknown stage, saw %q", table)
+	}
+	if table := serve("prod"); table != "prod-table" {
+		t.Errorf("Expected a known stage to keep its variables, saw %q", table)
+	}
+}
+
+func TestRegexWildcardRoutes(t *testing.T) {
+	router := New()
+	router.GET("/users/:id(\\d+)", simpleHandler).Name("user")
+
+	r, _ := newRequest("GET", "/__stage__/users/42", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected /users/42 to match, saw %d", w.Code)
+	}
+
+	r, _ = newRequest("GET", "/__stage__/users/abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for /users/abc, saw %d", w.Code)
+	}
+
+	if url, err := router.URLFor("user", map[string]string{"id": "42"}); err != nil || url != "/users/42" {
+		t.Errorf("Expected URLFor to build /users/42, saw %s (%v)", url, err)
+	}
+}
+
+func TestHandleOPTIONS(t *testing.T) {
+	router := New()
+	router.HeadCanUseGet = false
+	router.GET("/user", simpleHandler)
+	router.POST("/user", simpleHandler)
+	router.GET("/user/:id", simpleHandler)
+	router.OPTIONS("/user/:id", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := newRequest("OPTIONS", path, nil)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve("/__stage__/user"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 without HandleOPTIONS, saw %d", w.Code)
+	}
+
+	router.HandleOPTIONS = true
+	w := serve("/__stage__/user")
+	if allow := w.Header().Get("Allow"); w.Code != http.StatusNoContent || allow != "GET, POST, OPTIONS" {
+		t.Errorf("Expected 204 with Allow: GET, POST, OPTIONS, saw %d with %q", w.Code, allow)
+	}
+	if w := serve("/__stage__/user/5"); w.Code != http.StatusOK {
+		t.Errorf("Expected the OPTIONS route to take precedence, saw %d", w.Code)
+	}
+	if w := serve("/__stage__/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown path, saw %d", w.Code)
+	}
+
+	router.OptionsHandler = func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 202}, nil
+	}
+	if w := serve("/__stage__/user"); w.Code != 202 {
+		t.Errorf("Expected OptionsHandler to take precedence, saw %d", w.Code)
+	}
+}
+
+func TestHandleOPTIONSMethodNotAllowed(t *testing.T) {
+	router := New()
+	router.HeadCanUseGet = false
+	router.HandleOPTIONS = true
+	router.GET("/user", simpleHandler)
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := newRequest(method, "/__stage__/user", nil)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("OPTIONS")
+	if allow := w.Header().Get("Allow"); w.Code != http.StatusNoContent || allow != "GET, OPTIONS" {
+		t.Errorf("OPTIONS expected 204 with Allow: GET, OPTIONS, saw %d with %q", w.Code, allow)
+	}
+	w = serve("POST")
+	if allow := w.Header().Get("Allow"); w.Code != http.StatusMethodNotAllowed || allow != "GET" {
+		t.Errorf("POST expected 405 with Allow: GET, saw %d with %q", w.Code, allow)
+	}
+}
+
+func TestLookupMalformedPath(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/users", simpleHandler)
+
+	for _, path := range []string{"", "users"} {
+		res, err := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: path})
+		if err != nil || res.StatusCode != http.StatusNotFound {
+			t.Errorf("Path %q expected 404, saw %d, %v", path, res.StatusCode, err)
+		}
+	}
+
+	router.GET("/", simpleHandler)
+	res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET"})
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected an empty path to be served as /, saw %d", res.StatusCode)
+	}
+}
+
+func TestLookupMatchedPattern(t *testing.T) {
+	router := New()
+	router.GET("/hello/:name", simpleHandler)
+	router.GET("/files/*path", simpleHandler)
+
+	tests := []struct {
+		method, path, pattern string
+	}{
+		{"GET", "/prod/hello/bob", "/hello/:name"},
+		{"GET", "/prod/files/a/b.txt", "/files/*path"},
+		{"POST", "/prod/hello/bob", "/hello/:name"},
+		{"GET", "/prod/missing", ""},
+	}
+	for _, test := range tests {
+		lr, _ := router.Lookup(events.APIGatewayProxyRequest{HTTPMethod: test.method, Path: test.path})
+		if lr.MatchedPattern != test.pattern {
+			t.Errorf("%s %s expected pattern %q, saw %q", test.method, test.path, test.pattern, lr.MatchedPattern)
+		}
+	}
+}
+
+func TestSetRedirectMethodBehavior(t *testing.T) {
+	router := New()
+	router.GET("/slash/", simpleHandler)
+	router.POST("/slash/", simpleHandler)
+	router.SetRedirectMethodBehavior("POST", Redirect307)
+
+	serve := func(method string) int {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(method, "/__stage__/slash", nil)
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve("GET"); code != http.StatusMovedPermanently {
+		t.Errorf("GET expected the default 301, saw %d", code)
+	}
+	if code := serve("POST"); code != http.StatusTemporaryRedirect {
+		t.Errorf("POST expected 307, saw %d", code)
+	}
+
+	router.ClearRedirectMethodBehavior("POST")
+	if code := serve("POST"); code != http.StatusMovedPermanently {
+		t.Errorf("POST expected the default 301 once cleared, saw %d", code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an unknown method")
+		}
+	}()
+	router.SetRedirectMethodBehavior("post", Redirect307)
+}
+
 // func TestEscapedRoutes(t *testing.T) {
 // 	type testcase struct {
 // 		Route      string
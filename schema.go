@@ -0,0 +1,147 @@
+package lambdarouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// jsonSchema is the subset of JSON Schema supported by Route.Schema: type,
+// required, properties, items, enum, minLength, maxLength, minimum and maximum.
+type jsonSchema struct {
+	Type       string                 `json:"type"`
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Items      *jsonSchema            `json:"items"`
+	Enum       []interface{}          `json:"enum"`
+	MinLength  *int                   `json:"minLength"`
+	MaxLength  *int                   `json:"maxLength"`
+	Minimum    *float64               `json:"minimum"`
+	Maximum    *float64               `json:"maximum"`
+}
+
+// schemaError describes why the value at Path does not match the schema. Path is
+// a JSON pointer into the request body.
+type schemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func compileSchema(schema string) (*jsonSchema, error) {
+	var s jsonSchema
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *jsonSchema) validate(v interface{}, path string, errs []schemaError) []schemaError {
+	fail := func(format string, args ...interface{}) []schemaError {
+		return append(errs, schemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !matchesType(s.Type, v) {
+		return fail("expected %s", s.Type)
+	}
+
+	if len(s.Enum) != 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if reflect.DeepEqual(allowed, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = fail("must be one of %v", s.Enum)
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				errs = append(errs, schemaError{Path: path + "/" + name, Message: "is required"})
+			}
+		}
+		for name, property := range s.Properties {
+			if child, ok := value[name]; ok {
+				errs = property.validate(child, path+"/"+name, errs)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, child := range value {
+				errs = s.Items.validate(child, path+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case string:
+		length := len([]rune(value))
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = fail("must be at most %d characters long", *s.MaxLength)
+		}
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			errs = fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			errs = fail("must be at most %v", *s.Maximum)
+		}
+	}
+
+	return errs
+}
+
+func matchesType(schemaType string, v interface{}) bool {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return schemaType == "object"
+	case []interface{}:
+		return schemaType == "array"
+	case string:
+		return schemaType == "string"
+	case bool:
+		return schemaType == "boolean"
+	case float64:
+		return schemaType == "number" || (schemaType == "integer" && value == float64(int64(value)))
+	case nil:
+		return schemaType == "null"
+	}
+	return false
+}
+
+// validateBody checks the body of req against schema. It returns a 400 response
+// for a body which is not JSON, a 422 response listing the validation errors, or
+// nil when the body is valid.
+func validateBody(req events.APIGatewayProxyRequest, schema *jsonSchema) *events.APIGatewayProxyResponse {
+	data, err := RequestBody(req)
+	if err != nil {
+		return &events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error": "Bad Request"}`}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return &events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error": "Bad Request"}`}
+	}
+
+	errs := schema.validate(v, "", nil)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	body, _ := json.Marshal(struct {
+		Error  string        `json:"error"`
+		Errors []schemaError `json:"errors"`
+	}{"Unprocessable Entity", errs})
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 422,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,64 @@
+package lambdarouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteSchema(t *testing.T) {
+	router := New()
+	router.POST("/users", simpleHandler).Schema(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := newRequest("POST", "/__stage__/users", strings.NewReader(body))
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post(`{"name": "bob", "tags": ["a"]}`); w.Code != http.StatusNoContent {
+		t.Errorf("Expected valid body to reach the handler, saw code %d", w.Code)
+	}
+
+	w := post(`{"tags": ["a", 2]}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected code 422 for an invalid body, saw %d", w.Code)
+	}
+
+	var res struct {
+		Errors []schemaError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]bool{}
+	for _, e := range res.Errors {
+		paths[e.Path] = true
+	}
+	if !paths["/name"] || !paths["/tags/1"] || len(paths) != 2 {
+		t.Errorf("Expected errors for /name and /tags/1, saw %+v", res.Errors)
+	}
+
+	if w := post(`not json`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected code 400 for a body which is not JSON, saw %d", w.Code)
+	}
+}
+
+func TestRouteInvalidSchema(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("Invalid schema should have caused a panic")
+		}
+	}()
+	New().POST("/users", simpleHandler).Schema(`{"type": `)
+}
@@ -0,0 +1,44 @@
+package lambdarouter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AsServeMux returns an http.ServeMux serving the routes of the router, for tooling
+// built around the standard library routing. This is best-effort: only the static
+// patterns are registered on their own, as plain ServeMux paths all calling back into
+// ServeHTTP, and a catch-all sends every other request to ServeHTTP as well. When
+// serving locally, the stage segment can not be expressed as a plain path, so only the
+// catch-all is registered.
+//
+// Routes added to the router afterwards are served through the catch-all.
+func (t *TreeMux) AsServeMux() *http.ServeMux {
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+
+	mux := http.NewServeMux()
+	registered := map[string]bool{}
+	if t.path == "" {
+		t.root.walk(func(n *node) {
+			// A trailing slash makes the ServeMux match the whole subtree, which is
+			// still served by ServeHTTP.
+			if isStaticPattern(n.pattern) && !registered[n.pattern] {
+				registered[n.pattern] = true
+				mux.Handle(n.pattern, t)
+			}
+		})
+	}
+	if !registered["/"] {
+		mux.Handle("/", t)
+	}
+	return mux
+}
+
+// isStaticPattern reports whether pattern has neither wildcards nor catch-alls, and
+// can be registered on an http.ServeMux as is.
+func isStaticPattern(pattern string) bool {
+	return !strings.ContainsAny(pattern, ":*\\{} \t")
+}
@@ -0,0 +1,68 @@
+package lambdarouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAsServeMux(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/", simpleHandler)
+	router.GET("/users", simpleHandler)
+	router.POST("/users", simpleHandler)
+	router.GET("/users/:id", simpleHandler)
+	router.GET("/files/*path", simpleHandler)
+	router.GET("/docs/", simpleHandler)
+	mux := router.AsServeMux()
+
+	tests := []struct {
+		path    string
+		pattern string
+		code    int
+	}{
+		{"/", "/", 204},
+		{"/users", "/users", 204},
+		{"/users/5", "/", 204},
+		{"/files/a/b", "/", 204},
+		{"/docs/", "/docs/", 204},
+		{"/docs/missing", "/docs/", 404},
+		{"/missing", "/", 404},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.path, nil)
+		if _, pattern := mux.Handler(r); pattern != test.pattern {
+			t.Errorf("%s expected pattern %s, saw %s", test.path, test.pattern, pattern)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s expected status %d, saw %d", test.path, test.code, w.Code)
+		}
+	}
+}
+
+func TestAsServeMuxLocalStage(t *testing.T) {
+	router := New()
+	router.GET("/users", simpleHandler)
+	mux := router.AsServeMux()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/__stage__/users", 204},
+		{"/__stage__/missing", 404},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.path, nil)
+		if _, pattern := mux.Handler(r); pattern != "/" {
+			t.Errorf("%s expected pattern /, saw %s", test.path, pattern)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s expected status %d, saw %d", test.path, test.code, w.Code)
+		}
+	}
+}
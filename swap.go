@@ -0,0 +1,62 @@
+package lambdarouter
+
+// routeTable is a route tree with the names of its routes.
+type routeTable struct {
+	root        *node
+	namedRoutes map[string]*Route
+}
+
+// Swap replaces all the routes of the router with the ones newRoutes registers on
+// it, for example after loading new route definitions. The routes are added to a
+// new tree while the current one keeps serving requests, and it replaces the
+// current one at once, so that every request sees either the old routes or the new
+// ones. Requests already running finish with their handler.
+//
+//	router.Swap(func(t *lambdarouter.TreeMux) {
+//		for _, def := range defs {
+//			t.Handle(def.Method, def.Path, plugins[def.Handler])
+//		}
+//	})
+//
+// Swap requires SafeAddRoutesWhileRunning, and panics without it. Options set by
+// newRoutes apply right away, only the routes wait for the swap. If newRoutes
+// panics, the current routes are kept.
+func (t *TreeMux) Swap(newRoutes func(*TreeMux)) {
+	if !t.SafeAddRoutesWhileRunning {
+		panic("Swap requires SafeAddRoutesWhileRunning")
+	}
+	t.swapMutex.Lock()
+	defer t.swapMutex.Unlock()
+
+	t.mutex.Lock()
+	t.building = &routeTable{root: &node{path: "/"}}
+	t.mutex.Unlock()
+	defer func() {
+		t.mutex.Lock()
+		t.building = nil
+		t.mutex.Unlock()
+	}()
+
+	newRoutes(t)
+
+	t.mutex.Lock()
+	t.root, t.namedRoutes = t.building.root, t.building.namedRoutes
+	t.mutex.Unlock()
+}
+
+// routeRoot returns the tree routes are added to, which is the one Swap builds
+// while it runs. The caller holds the write lock.
+func (t *TreeMux) routeRoot() *node {
+	if t.building != nil {
+		return t.building.root
+	}
+	return t.root
+}
+
+// routeNames returns the names of the routes of the tree returned by routeRoot.
+func (t *TreeMux) routeNames() *map[string]*Route {
+	if t.building != nil {
+		return &t.building.namedRoutes
+	}
+	return &t.namedRoutes
+}
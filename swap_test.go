@@ -0,0 +1,85 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSwap(t *testing.T) {
+	statusHandler := func(code int) HandlerFunc {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			return events.APIGatewayProxyResponse{StatusCode: code}, nil
+		}
+	}
+
+	router := New()
+	router.SafeAddRoutesWhileRunning = true
+	router.GET("/old", statusHandler(200)).Name("route")
+
+	var stop int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stop) == 0 {
+				r, _ := http.NewRequest("GET", "/__stage__/old", nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+				if w.Code != 200 && w.Code != 404 {
+					t.Errorf("Expected 200 or 404 during the swaps, saw %d", w.Code)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		router.Swap(func(t *TreeMux) {
+			t.GET("/old", statusHandler(200)).Name("route")
+		})
+	}
+	router.Swap(func(t *TreeMux) {
+		t.GET("/new", statusHandler(201)).Name("route")
+	})
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+
+	for path, code := range map[string]int{"/__stage__/old": 404, "/__stage__/new": 201} {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("Expected %d for %s after the swap, saw %d", code, path, w.Code)
+		}
+	}
+	if url, err := router.URLFor("route", nil); err != nil || url != "/new" {
+		t.Errorf("Expected the route name to follow the swap, saw %s (%v)", url, err)
+	}
+
+	// A panic while building keeps the current routes.
+	func() {
+		defer func() { recover() }()
+		router.Swap(func(t *TreeMux) {
+			t.GET("/broken", statusHandler(200))
+			panic("bad config")
+		})
+	}()
+	r, _ := http.NewRequest("GET", "/__stage__/new", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 201 {
+		t.Errorf("Expected the routes to be kept after a failed swap, saw %d", w.Code)
+	}
+	router.GET("/added", statusHandler(202))
+	r, _ = http.NewRequest("GET", "/__stage__/added", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 202 {
+		t.Errorf("Expected routes added after a failed swap to be served, saw %d", w.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package lambdarouter
+
+import (
+	"strings"
+	"sync"
+)
+
+// routeTags holds the tags disabled with SetTagEnabled, which may change while
+// requests are served.
+type routeTags struct {
+	mutex    sync.RWMutex
+	disabled map[string]bool
+}
+
+// Tag labels the route with tags, so that SetTagEnabled can turn it off and on
+// together with the other routes of a feature.
+//
+//	router.GET("/search/v2", searchV2).Tag("beta")
+func (r *Route) Tag(tags ...string) *Route {
+	r.tags = append(r.tags, tags...)
+	return r
+}
+
+// SetTagEnabled enables or disables the routes tagged with tag. Requests to a route
+// with a disabled tag are served as if the route did not exist, with a 404 Not Found
+// or a 405 Method Not Allowed listing the other methods of the path, until the tag is
+// enabled again. Tags are enabled by default. It is safe to call while
+// serving requests.
+func (t *TreeMux) SetTagEnabled(tag string, enabled bool) {
+	t.tags.mutex.Lock()
+	defer t.tags.mutex.Unlock()
+	if enabled {
+		delete(t.tags.disabled, tag)
+		return
+	}
+	if t.tags.disabled == nil {
+		t.tags.disabled = make(map[string]bool)
+	}
+	t.tags.disabled[tag] = true
+}
+
+// routeDisabled reports whether one of the tags of route is disabled.
+func (t *TreeMux) routeDisabled(route *Route) bool {
+	if route == nil || len(route.tags) == 0 {
+		return false
+	}
+	t.tags.mutex.RLock()
+	defer t.tags.mutex.RUnlock()
+	for _, tag := range route.tags {
+		if t.tags.disabled[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedMethods returns the methods of n in the format of node.allow, leaving out
+// the methods whose routes all have a disabled tag.
+func (t *TreeMux) allowedMethods(n *node) string {
+	t.tags.mutex.RLock()
+	none := len(t.tags.disabled) == 0
+	t.tags.mutex.RUnlock()
+	if none {
+		return n.allow
+	}
+
+	var methods []string
+	for _, method := range sortedMethods(n.leafHandler) {
+		enabled := n.leafHandler[method] != nil && !t.routeDisabled(n.leafRoute[method])
+		for _, hr := range n.headerRoutes[method] {
+			enabled = enabled || !t.routeDisabled(hr.route)
+		}
+		if enabled {
+			methods = append(methods, method)
+		}
+	}
+	return strings.Join(methods, " ")
+}
@@ -0,0 +1,78 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestTags(t *testing.T) {
+	router := newLambdaRouter()
+	router.GET("/search/v2", simpleHandler).Tag("beta")
+	router.GET("/search", simpleHandler)
+
+	serve := func(path string) int {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: path, Path: path})
+		return res.StatusCode
+	}
+
+	if code := serve("/search/v2"); code != http.StatusNoContent {
+		t.Errorf("Expected the beta route to be enabled by default, saw %d", code)
+	}
+
+	router.SetTagEnabled("beta", false)
+	if code := serve("/search/v2"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 with the beta tag disabled, saw %d", code)
+	}
+	if code := serve("/search"); code != http.StatusNoContent {
+		t.Errorf("Expected the untagged route to be unaffected, saw %d", code)
+	}
+
+	router.SetTagEnabled("beta", true)
+	if code := serve("/search/v2"); code != http.StatusNoContent {
+		t.Errorf("Expected the beta route back once enabled, saw %d", code)
+	}
+}
+
+func TestTagsAllow(t *testing.T) {
+	router := newLambdaRouter()
+	router.HandleOPTIONS = true
+	router.GET("/items", simpleHandler)
+	router.POST("/items", simpleHandler).Tag("beta")
+	router.PUT("/items", simpleHandler).Tag("beta")
+	router.DELETE("/items/:id", simpleHandler).Tag("beta")
+	router.SetTagEnabled("beta", false)
+
+	serve := func(method, path string) events.APIGatewayProxyResponse {
+		res, _ := router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: method, Resource: path, Path: path})
+		return res
+	}
+
+	res := serve("POST", "/items")
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for a disabled method, saw %d", res.StatusCode)
+	}
+	if allow := res.Headers["Allow"]; allow != "GET HEAD" {
+		t.Errorf("Expected Allow to leave out the disabled methods, saw %q", allow)
+	}
+
+	res = serve("OPTIONS", "/items")
+	if allow := res.Headers["Allow"]; allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected OPTIONS to leave out the disabled methods, saw %q", allow)
+	}
+
+	if res = serve("DELETE", "/items/5"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 when every method is disabled, saw %d", res.StatusCode)
+	}
+	if res = serve("OPTIONS", "/items/5"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for OPTIONS when every method is disabled, saw %d", res.StatusCode)
+	}
+
+	router.SetTagEnabled("beta", true)
+	res = serve("PATCH", "/items")
+	if allow := res.Headers["Allow"]; allow != "GET HEAD POST PUT" {
+		t.Errorf("Expected Allow to list the methods back once enabled, saw %q", allow)
+	}
+}
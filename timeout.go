@@ -0,0 +1,64 @@
+package lambdarouter
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SetGlobalTimeout limits every handler to run for at most d. Handlers receive a
+// context with the corresponding deadline, which is never later than the deadline of
+// the Lambda invocation itself. When d expires before the handler returns, the
+// router answers with 504 Gateway Timeout. A value of 0 disables the timeout.
+//
+// The handler is not interrupted and should return once its context is done. Reads
+// of a body streamed with Route.StreamBody fail from the moment the router answered.
+func (t *TreeMux) SetGlobalTimeout(d time.Duration) {
+	t.globalTimeout = d
+}
+
+type handlerResult struct {
+	res      events.APIGatewayProxyResponse
+	err      error
+	panicked interface{}
+}
+
+// handlerTimeout returns the time the handler of lr may run for, or 0 when it is
+// not limited.
+func (t *TreeMux) handlerTimeout(lr LookupResult) time.Duration {
+	if lr.route != nil && lr.route.timeout != 0 {
+		return lr.route.timeout
+	}
+	return t.globalTimeout
+}
+
+func (t *TreeMux) callHandler(ctx context.Context, req events.APIGatewayProxyRequest, handler HandlerFunc, timeout time.Duration) (events.APIGatewayProxyResponse, error) {
+	if timeout <= 0 {
+		return handler(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan handlerResult, 1)
+	go func() {
+		var result handlerResult
+		defer func() {
+			// Hand the panic over to the calling goroutine, where the panic handlers live.
+			result.panicked = recover()
+			done <- result
+		}()
+		result.res, result.err = handler(ctx, req)
+	}()
+
+	select {
+	case result := <-done:
+		if result.panicked != nil {
+			panic(result.panicked)
+		}
+		return result.res, result.err
+	case <-ctx.Done():
+		return LambdaGatewayTimeout(ctx, req)
+	}
+}
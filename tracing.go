@@ -0,0 +1,29 @@
+package lambdarouter
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// TraceIDContextKey is the context key under which ServeLambda and ServeHTTP store
+// the X-Amzn-Trace-Id header of the request. It is the key the Lambda runtime and
+// the AWS X-Ray SDK use for the trace header, so the calls made with the context
+// of a handler join the trace of the request.
+const TraceIDContextKey = "x-amzn-trace-id"
+
+// TraceID returns the X-Ray trace header of the request served with ctx, such as
+// "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1", or an empty string.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(TraceIDContextKey).(string)
+	return id
+}
+
+// withTraceID stores the X-Amzn-Trace-Id header of req in ctx. The context is left
+// as is when the request has none, keeping the trace header set by the runtime.
+func withTraceID(ctx context.Context, req events.APIGatewayProxyRequest) context.Context {
+	if id, ok := headerValue(req.Headers, "X-Amzn-Trace-Id"); ok && id != "" {
+		return context.WithValue(ctx, TraceIDContextKey, id)
+	}
+	return ctx
+}
@@ -0,0 +1,53 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestTraceID(t *testing.T) {
+	const traceID = "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"
+
+	var seen string
+	handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		seen = TraceID(ctx)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+	}
+
+	router := newLambdaRouter()
+	router.GET("/user", handler)
+	router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Resource:   "/user",
+		Path:       "/user",
+		Headers:    map[string]string{"x-amzn-trace-id": traceID},
+	})
+	if seen != traceID {
+		t.Errorf("Expected the trace id in the context of ServeLambda, saw %q", seen)
+	}
+
+	seen = "unset"
+	router.ServeLambda(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/user", Path: "/user"})
+	if seen != "" {
+		t.Errorf("Expected no trace id without the header, saw %q", seen)
+	}
+
+	r, _ := http.NewRequest("GET", "/prod/user", nil)
+	r.Header.Set("X-Amzn-Trace-Id", traceID)
+	event, _ := RequestToLambda(r)
+	if event.Headers["X-Amzn-Trace-Id"] != traceID {
+		t.Errorf("Expected RequestToLambda to copy the trace header, saw %v", event.Headers)
+	}
+
+	seen = ""
+	local := New()
+	local.GET("/user", handler)
+	local.ServeHTTP(httptest.NewRecorder(), r)
+	if seen != traceID {
+		t.Errorf("Expected the trace id in the context of ServeHTTP, saw %q", seen)
+	}
+}
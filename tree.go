@@ -2,6 +2,7 @@ package lambdarouter
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -14,7 +15,9 @@ type node struct {
 	staticIndices []byte
 	staticChild   []*node
 
-	// If none of the above match, check the wildcard children
+	// If none of the above match, check the wildcard children, first the ones
+	// constrained by a regular expression, in the order they were added.
+	regexChild    []*node
 	wildcardChild *node
 
 	// If none of the above match, then we use the catch-all, if applicable.
@@ -28,9 +31,21 @@ type node struct {
 	implicitHead bool
 	// If this node is the end of the URL, then call the handler, if applicable.
 	leafHandler map[string]HandlerFunc
+	// The per-route options of each handler, by method.
+	leafRoute map[string]*Route
+	// The routes constrained by request headers, by method. See Route.Header.
+	headerRoutes map[string][]headerRoute
+	// The Allow header for the methods of leafHandler, kept up to date by setHandler.
+	allow string
 
 	// The names of the parameters to apply.
 	leafWildcardNames []string
+
+	// The pattern the leaf was registered with, without the local stage.
+	pattern string
+
+	// The expression the segment must match, for the nodes of regexChild.
+	regex *regexp.Regexp
 }
 
 func (n *node) sortStaticChild(i int) {
@@ -45,17 +60,26 @@ func (n *node) setHandler(verb string, handler HandlerFunc, implicitHead bool) {
 	if n.leafHandler == nil {
 		n.leafHandler = make(map[string]HandlerFunc)
 	}
-	_, ok := n.leafHandler[verb]
-	if ok && (verb != "HEAD" || !n.implicitHead) {
+	// A nil handler is left by the routes constrained by headers. See Route.Header.
+	existing, ok := n.leafHandler[verb]
+	if ok && existing != nil && (verb != "HEAD" || !n.implicitHead) {
 		panic(fmt.Sprintf("%s already handles %s", n.path, verb))
 	}
 	n.leafHandler[verb] = handler
+	n.allow = strings.Join(sortedMethods(n.leafHandler), " ")
 
 	if verb == "HEAD" {
 		n.implicitHead = implicitHead
 	}
 }
 
+func (n *node) setRoute(verb string, route *Route) {
+	if n.leafRoute == nil {
+		n.leafRoute = make(map[string]*Route)
+	}
+	n.leafRoute[verb] = route
+}
+
 func (n *node) addPath(path string, wildcards []string, inStaticToken bool) *node {
 	leaf := len(path) == 0
 	if leaf {
@@ -127,12 +151,16 @@ func (n *node) addPath(path string, wildcards []string, inStaticToken bool) *nod
 		return n.catchAllChild
 	} else if c == ':' && !inStaticToken {
 		// Token starts with a :
-		thisToken = thisToken[1:]
+		name, expr := splitWildcard(thisToken[1:])
 
 		if wildcards == nil {
-			wildcards = []string{thisToken}
+			wildcards = []string{name}
 		} else {
-			wildcards = append(wildcards, thisToken)
+			wildcards = append(wildcards, name)
+		}
+
+		if expr != "" {
+			return n.regexWildcardChild(expr).addPath(remainingPath, wildcards, false)
 		}
 
 		if n.wildcardChild == nil {
@@ -191,6 +219,71 @@ func (n *node) addPath(path string, wildcards []string, inStaticToken bool) *nod
 	}
 }
 
+// patternSegment is one slash-separated segment of a route pattern.
+type patternSegment struct {
+	// The parameter name for wildcards and catch-alls, or the literal text of a
+	// static segment, without the backslash escaping its : or *.
+	text     string
+	expr     string // The regular expression constraining a wildcard, if any.
+	wildcard bool
+	catchAll bool
+}
+
+// splitPattern splits pattern at its slashes into static, wildcard and catch-all
+// segments, for the code rendering patterns in other forms.
+func splitPattern(pattern string) []patternSegment {
+	segments := strings.Split(pattern, "/")
+	split := make([]patternSegment, len(segments))
+	for i, segment := range segments {
+		split[i].text = segment
+		if len(segment) < 2 {
+			continue
+		}
+		switch segment[0] {
+		case ':':
+			split[i].text, split[i].expr = splitWildcard(segment[1:])
+			split[i].wildcard = true
+		case '*':
+			split[i].text = segment[1:]
+			split[i].catchAll = true
+		case '\\':
+			// An escaped : or * is a literal.
+			split[i].text = segment[1:]
+		}
+	}
+	return split
+}
+
+// splitWildcard splits a wildcard token, without its colon, into the name of the
+// parameter and the regular expression constraining it, as in id(\d+).
+func splitWildcard(token string) (name, expr string) {
+	i := strings.IndexByte(token, '(')
+	if i < 0 {
+		return token, ""
+	}
+	if i == 0 || token[len(token)-1] != ')' || len(token) == i+2 {
+		panic(fmt.Sprintf("Invalid regular expression in wildcard :%s", token))
+	}
+	return token[:i], token[i+1 : len(token)-1]
+}
+
+// regexWildcardChild returns the wildcard child constrained by expr, adding it when
+// needed. The expression must match the whole segment.
+func (n *node) regexWildcardChild(expr string) *node {
+	for _, child := range n.regexChild {
+		if child.path == expr {
+			return child
+		}
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("Invalid regular expression %s in wildcard: %s", expr, err))
+	}
+	child := &node{path: expr, regex: re}
+	n.regexChild = append(n.regexChild, child)
+	return child
+}
+
 func (n *node) splitCommonPrefix(existingNodeIndex int, path string) (*node, int) {
 	childNode := n.staticChild[existingNodeIndex]
 
@@ -237,10 +330,13 @@ func (n *node) search(method, path string) (found *node, handler HandlerFunc, pa
 	// }
 	pathLen := len(path)
 	if pathLen == 0 {
-		if len(n.leafHandler) == 0 {
-			return nil, nil, nil
-		} else {
+		if len(n.leafHandler) != 0 {
 			return n, n.leafHandler[method], nil
+		} else if n.catchAllChild != nil && len(n.catchAllChild.leafHandler) != 0 {
+			// A catch-all also matches an empty remainder.
+			return n.catchAllChild, n.catchAllChild.leafHandler[method], []string{""}
+		} else {
+			return nil, nil, nil
 		}
 	}
 
@@ -260,11 +356,11 @@ func (n *node) search(method, path string) (found *node, handler HandlerFunc, pa
 
 	// If we found a node and it had a valid handler, then return here. Otherwise
 	// let's remember that we found this one, but look for a better match.
-	if handler != nil {
+	if found != nil && found.handles(method) {
 		return
 	}
 
-	if n.wildcardChild != nil {
+	if n.wildcardChild != nil || n.regexChild != nil {
 		// Didn't find a static token, so check for a wildcard.
 		nextSlash := strings.IndexByte(path, '/')
 		if nextSlash < 0 {
@@ -275,29 +371,43 @@ func (n *node) search(method, path string) (found *node, handler HandlerFunc, pa
 		nextToken := path[nextSlash:]
 
 		if len(thisToken) > 0 { // Don't match on empty tokens.
-			wcNode, wcHandler, wcParams := n.wildcardChild.search(method, nextToken)
-			if wcHandler != nil || (found == nil && wcNode != nil) {
-				unescaped, err := unescape(thisToken)
-				if err != nil {
-					unescaped = thisToken
-				}
+			unescaped, err := unescape(thisToken)
+			if err != nil {
+				unescaped = thisToken
+			}
 
-				if wcParams == nil {
-					wcParams = []string{unescaped}
-				} else {
-					wcParams = append(wcParams, unescaped)
+			// The constrained wildcards come first, then the one matching anything.
+			for i := 0; i <= len(n.regexChild); i++ {
+				wildcard := n.wildcardChild
+				if i < len(n.regexChild) {
+					wildcard = n.regexChild[i]
+					if !wildcard.regex.MatchString(unescaped) {
+						continue
+					}
+				} else if wildcard == nil {
+					break
 				}
 
-				if wcHandler != nil {
-					return wcNode, wcHandler, wcParams
-				}
+				wcNode, wcHandler, wcParams := wildcard.search(method, nextToken)
+				wcHandles := wcNode != nil && wcNode.handles(method)
+				if wcHandles || (found == nil && wcNode != nil) {
+					if wcParams == nil {
+						wcParams = []string{unescaped}
+					} else {
+						wcParams = append(wcParams, unescaped)
+					}
+
+					if wcHandles {
+						return wcNode, wcHandler, wcParams
+					}
 
-				// Didn't actually find a handler here, so remember that we
-				// found a node but also see if we can fall through to the
-				// catchall.
-				found = wcNode
-				handler = wcHandler
-				params = wcParams
+					// Didn't actually find a handler here, so remember that we
+					// found a node but also see if we can fall through to the
+					// catchall.
+					found = wcNode
+					handler = wcHandler
+					params = wcParams
+				}
 			}
 		}
 	}
@@ -309,7 +419,7 @@ func (n *node) search(method, path string) (found *node, handler HandlerFunc, pa
 		handler = catchAllChild.leafHandler[method]
 		// Found a handler, or we found a catchall node without a handler.
 		// Either way, return it since there's nothing left to check after this.
-		if handler != nil || found == nil {
+		if catchAllChild.handles(method) || found == nil {
 			unescaped, err := unescape(path)
 			if err != nil {
 				unescaped = path
@@ -324,12 +434,19 @@ func (n *node) search(method, path string) (found *node, handler HandlerFunc, pa
 }
 
 func (n *node) dumpTree(prefix, nodeType string) string {
-	line := fmt.Sprintf("%s %02d %s%s [%d] %v wildcards %v\n", prefix, n.priority, nodeType, n.path,
-		len(n.staticChild), n.leafHandler, n.leafWildcardNames)
+	var methods string
+	if len(n.leafHandler) != 0 {
+		methods = "[" + strings.Join(sortedMethods(n.leafHandler), ",") + "]"
+	}
+	line := fmt.Sprintf("%s %02d %s%s [%d] %s wildcards %v\n", prefix, n.priority, nodeType, n.path,
+		len(n.staticChild), methods, n.leafWildcardNames)
 	prefix += "  "
 	for _, node := range n.staticChild {
 		line += node.dumpTree(prefix, "")
 	}
+	for _, node := range n.regexChild {
+		line += node.dumpTree(prefix, ":")
+	}
 	if n.wildcardChild != nil {
 		line += n.wildcardChild.dumpTree(prefix, ":")
 	}
@@ -338,3 +455,23 @@ func (n *node) dumpTree(prefix, nodeType string) string {
 	}
 	return line
 }
+
+// walk calls fn for n and all of its descendants which have handlers, in the same
+// order as dumpTree.
+func (n *node) walk(fn func(*node)) {
+	if len(n.leafHandler) != 0 {
+		fn(n)
+	}
+	for _, node := range n.staticChild {
+		node.walk(fn)
+	}
+	for _, node := range n.regexChild {
+		node.walk(fn)
+	}
+	if n.wildcardChild != nil {
+		n.wildcardChild.walk(fn)
+	}
+	if n.catchAllChild != nil {
+		n.catchAllChild.walk(fn)
+	}
+}
@@ -198,6 +198,8 @@ func TestTree(t *testing.T) {
 		map[string]string{"year": "2014", "month": "5", "post": "def/hij"})
 	testPath(t, tree, "/date/2014/5/def/hij/", "/date/:year/:month/*post",
 		map[string]string{"year": "2014", "month": "5", "post": "def/hij/"})
+	testPath(t, tree, "/date/2014/05/", "/date/:year/:month/*post",
+		map[string]string{"year": "2014", "month": "05", "post": ""})
 
 	testPath(t, tree, "/date/2014/ab%2f", "/date/:year/:month",
 		map[string]string{"year": "2014", "month": "ab/"})
@@ -222,7 +224,6 @@ func TestTree(t *testing.T) {
 
 	testPath(t, tree, "/ima/bcd/fgh", "", nil)
 	testPath(t, tree, "/date/2014//month", "", nil)
-	testPath(t, tree, "/date/2014/05/", "", nil) // Empty catchall should not match
 	testPath(t, tree, "/post//abc/page/2", "", nil)
 	testPath(t, tree, "/post/abc//page/2", "", nil)
 	testPath(t, tree, "/post/abc/page//2", "", nil)
@@ -314,6 +315,39 @@ func TestPanics(t *testing.T) {
 	twoPathPanic(":abc/ggg", ":def/ggg")
 }
 
+func TestRegexWildcards(t *testing.T) {
+	tree := &node{path: "/"}
+	addPath(t, tree, "/users/:id(\\d+)")
+	addPath(t, tree, "/users/:id(\\d+)/posts")
+	addPath(t, tree, "/users/me")
+	addPath(t, tree, "/users/:name")
+	addPath(t, tree, "/items/:sku([a-z]{3}-\\d+)")
+	addPath(t, tree, "/items/*path")
+	addPath(t, tree, "/codes/:code(\\d+)")
+
+	testPath(t, tree, "/users/42", "/users/:id(\\d+)", map[string]string{"id": "42"})
+	testPath(t, tree, "/users/42/posts", "/users/:id(\\d+)/posts", map[string]string{"id": "42"})
+	testPath(t, tree, "/users/me", "/users/me", nil)
+	testPath(t, tree, "/users/abc", "/users/:name", map[string]string{"name": "abc"})
+	testPath(t, tree, "/users/42abc", "/users/:name", map[string]string{"name": "42abc"})
+	testPath(t, tree, "/users/abc/posts", "", nil)
+	testPath(t, tree, "/items/abc-12", "/items/:sku([a-z]{3}-\\d+)", map[string]string{"sku": "abc-12"})
+	testPath(t, tree, "/items/abcd-12", "/items/*path", map[string]string{"path": "abcd-12"})
+	testPath(t, tree, "/codes/123", "/codes/:code(\\d+)", map[string]string{"code": "123"})
+	testPath(t, tree, "/codes/abc", "", nil)
+
+	for _, path := range []string{"users/:id(\\d+", "users/:(\\d+)", "users/:id()", "users/:id([)"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for the invalid wildcard %s", path)
+				}
+			}()
+			(&node{path: "/"}).addPath(path, nil, false)
+		}()
+	}
+}
+
 func BenchmarkTreeNullRequest(b *testing.B) {
 	b.ReportAllocs()
 	tree := &node{
@@ -0,0 +1,298 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type TreeMux struct {
+	StageVariables StageVariables
+	root           *node
+	mutex          sync.RWMutex
+
+	// defaultStage names the StageVariables of unknown stages. See SetDefaultStage.
+	defaultStage string
+
+	Group
+
+	// The default PanicHandler just returns a 500 code.
+	PanicHandler PanicHandler
+
+	// BadRequestHandler answers the malformed requests ServeHTTP refuses to route, such as
+	// the ones without a method. When nil, SimpleBadRequestHandler is used.
+	BadRequestHandler BadRequestHandler
+
+	// ServeLambdaPanicHandler recovers the panics of the handlers called by ServeLambda.
+	// New sets it to SimpleServeLambdaPanicHandler, and nil lets the panics through.
+	ServeLambdaPanicHandler ServeLambdaPanicHandler
+
+	// LambdaPanicHandler recovers the panics of the handler returned by LambdaHandler.
+	// New sets it to SimpleLambdaPanicHandler, and nil lets the panics through.
+	LambdaPanicHandler LambdaPanicHandler
+
+	// The default NotFoundHandler is http.NotFound.
+	NotFoundHandler HandlerFunc
+
+	// notFoundHandlers override NotFoundHandler by method. See SetNotFoundHandlerForMethod.
+	notFoundHandlers map[string]HandlerFunc
+
+	// Any OPTIONS request that matches a path without its own OPTIONS handler will use this handler,
+	// if set, instead of calling MethodNotAllowedHandler.
+	OptionsHandler HandlerFunc
+
+	// HandleOPTIONS answers the OPTIONS requests of paths without their own OPTIONS handler,
+	// when OptionsHandler is not set, with 204 No Content and an Allow header listing the
+	// methods of the path, such as "GET, POST, OPTIONS". It is false by default.
+	HandleOPTIONS bool
+
+	// Websocket receives the WebSocket events served by LambdaHandler.
+	Websocket *WebsocketMux
+
+	authorizer func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error)
+
+	// authorizerFailureMode handles the errors of the authorizer. See SetAuthorizerFailureMode.
+	authorizerFailureMode AuthorizerFailureMode
+
+	// authorizerCache holds the responses of the authorizer. See SetAuthorizerCacheTTL.
+	authorizerCache *authorizerCache
+	// authorizerIdentitySource keys authorizerCache. See SetAuthorizerIdentitySource.
+	authorizerIdentitySource []string
+
+	// warmerDetector recognizes the warming pings. See SetWarmerDetector.
+	warmerDetector func(raw json.RawMessage) bool
+	// warmup is called for each warming ping. See SetWarmup.
+	warmup func(ctx context.Context) error
+
+	// AuthorizeOptions runs the authorizer for OPTIONS requests too. By default they
+	// skip it, since browsers send CORS preflight requests without credentials.
+	AuthorizeOptions bool
+
+	// correlationHeaders are copied from the request to the response. See SetCorrelationHeaders.
+	correlationHeaders []string
+
+	// paramTransformers normalize captured parameters, by name. See Transform.
+	paramTransformers map[string][]func(string) string
+
+	// globalTimeout bounds the run time of every handler. See SetGlobalTimeout.
+	globalTimeout time.Duration
+
+	// concurrency holds one token per handler running in ServeHTTP. See SetMaxConcurrency.
+	concurrency       chan struct{}
+	concurrencyPolicy ConcurrencyPolicy
+
+	// rateLimiter throttles the requests reaching handlers. See SetRateLimit.
+	rateLimiter *rateLimiter
+	// stageRateLimiters override rateLimiter by stage. See SetStageRateLimit.
+	stageRateLimiters map[string]*rateLimiter
+
+	// maintenance turns requests away while it is on. See SetMaintenance.
+	maintenance maintenance
+
+	// tags hides the routes of disabled tags. See SetTagEnabled.
+	tags routeTags
+
+	// coverage records the routes which served requests. See EnableCoverageTracking.
+	coverage *routeCoverage
+
+	// serverTiming adds the Server-Timing header to responses. See EnableServerTiming.
+	serverTiming bool
+
+	// matchedRouteHeader adds the X-Matched-Route header to responses. See
+	// EnableMatchedRouteHeader.
+	matchedRouteHeader bool
+
+	// observer is told about every request served. See SetObserver.
+	observer func(ctx context.Context, data ObservationData)
+
+	// cors adds the CORS headers and answers preflight requests. See EnableCORS.
+	cors *CORSConfig
+
+	// namedRoutes holds the routes by name. See Route.Name and URLFor.
+	namedRoutes map[string]*Route
+
+	// building receives the routes added while Swap runs. See Swap.
+	building  *routeTable
+	swapMutex sync.Mutex
+
+	// coldStartHeader flags the first response of the process. See EnableColdStartHeader.
+	coldStartHeader bool
+
+	// rootRedirect redirects requests to the root path. See SetRootRedirect.
+	rootRedirect *rootRedirect
+
+	// canonicalHost redirects requests to another host or scheme. See SetCanonicalHost.
+	canonicalHost *canonicalHost
+
+	// MethodNotAllowedHandler is called when a pattern matches, but that
+	// pattern does not have a handler for the requested method. The default
+	// handler just writes the status code http.StatusMethodNotAllowed and adds
+	// the required Allowed header.
+	// The methods parameter contains the map of each method to the corresponding
+	// handler function.
+	MethodNotAllowedHandler func(context.Context, events.APIGatewayProxyRequest, string) (events.APIGatewayProxyResponse, error)
+
+	// HeadCanUseGet allows the router to use the GET handler to respond to
+	// HEAD requests if no explicit HEAD handler has been added for the
+	// matching pattern. This is true by default.
+	HeadCanUseGet bool
+
+	// RedirectCleanPath allows the router to try clean the current request path,
+	// if no handler is registered for it, using CleanPath from github.com/dimfeld/httppath.
+	// This is true by default.
+	RedirectCleanPath bool
+
+	// RedirectTrailingSlash enables automatic redirection in case router doesn't find a matching route
+	// for the current request path but a handler for the path with or without the trailing
+	// slash exists. This is true by default.
+	RedirectTrailingSlash bool
+
+	// RemoveCatchAllTrailingSlash removes the trailing slash when a catch-all pattern
+	// is matched, if set to true. By default, catch-all paths are never redirected.
+	RemoveCatchAllTrailingSlash bool
+
+	// RedirectBehavior sets the default redirect behavior when RedirectTrailingSlash or
+	// RedirectCleanPath are true. The default value is Redirect301.
+	RedirectBehavior RedirectBehavior
+
+	// RedirectMethodBehavior overrides the default behavior for a particular HTTP method.
+	// The key is the method name, and the value is the behavior to use for that method.
+	// See SetRedirectMethodBehavior.
+	RedirectMethodBehavior map[string]RedirectBehavior
+
+	// PathSource determines from where the router gets its path to search.
+	// By default it pulls the data from the RequestURI member, but this can
+	// be overridden to use URL.Path instead.
+	//
+	// There is a small tradeoff here. Using RequestURI allows the router to handle
+	// encoded slashes (i.e. %2f) in the URL properly, while URL.Path provides
+	// better compatibility with some utility functions in the http
+	// library that modify the Request before passing it to the router.
+	PathSource PathSource
+
+	// EscapeAddedRoutes controls URI escaping behavior when adding a route to the tree.
+	// If set to true, the router will add both the route as originally passed, and
+	// a version passed through URL.EscapedPath. This behavior is disabled by default.
+	EscapeAddedRoutes bool
+
+	// ForwardedHeader names the header ServeHTTP adds the address of the client to.
+	// It defaults to X-Forwarded-For, or Forwarded when ForwardedFormat is RFC7239.
+	ForwardedHeader string
+
+	// ForwardedFormat selects how the address is added to ForwardedHeader.
+	ForwardedFormat ForwardedFormat
+
+	// QueryParamMode selects which value of a repeated query parameter ServeHTTP puts
+	// in QueryStringParameters. The default is QueryParamFirst.
+	QueryParamMode QueryParamMode
+
+	// MaxRequestBytes limits the size of request bodies. Requests with a larger body
+	// are answered with 413 Request Entity Too Large without calling the handler.
+	// Route.MaxBody overrides it for a single route. The default of 0 means no limit.
+	MaxRequestBytes int64
+
+	// MaxParams is the maximum number of wildcards and catch-alls allowed in a
+	// registered pattern. Registering a pattern with more panics. A value of 0
+	// means no limit. New sets it to DefaultMaxParams.
+	MaxParams int
+
+	// If present, handlers and the authorizer can read the values of this context in
+	// addition to the ones of the request context, which keeps its deadline and
+	// cancellation. Use it to share dependencies such as configuration or clients.
+	DefaultContext context.Context
+
+	// SafeAddRoutesWhileRunning tells the router to protect all accesses to the tree with an RWMutex. This is only needed
+	// if you are going to add routes after the router has already begun serving requests. There is a potential
+	// performance penalty at high load. Routes are always added under the write lock, so this only makes the
+	// lookups take the read lock.
+	SafeAddRoutesWhileRunning bool
+}
+
+// defaultValueContext looks up the values missing from its Context in defaults.
+type defaultValueContext struct {
+	context.Context
+	defaults context.Context
+}
+
+func (c defaultValueContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.defaults.Value(key)
+}
+
+// withDefaultContext adds the values of DefaultContext to ctx.
+func (t *TreeMux) withDefaultContext(ctx context.Context) context.Context {
+	if t.DefaultContext == nil {
+		return ctx
+	}
+	return defaultValueContext{ctx, t.DefaultContext}
+}
+
+func (t *TreeMux) setDefaultRequestContext(r *http.Request) *http.Request {
+	if t.DefaultContext != nil {
+		r = r.WithContext(t.DefaultContext)
+	}
+
+	return r
+}
+
+type ContextMux struct {
+	*TreeMux
+	*ContextGroup
+}
+
+// NewContextMux returns a TreeMux preconfigured to work with standard http
+// Handler functions and context objects.
+func NewContextMux() *ContextMux {
+	mux := New()
+	cg := mux.UsingContext()
+
+	return &ContextMux{
+		TreeMux:      mux,
+		ContextGroup: cg,
+	}
+}
+
+func (cm *ContextMux) NewGroup(path string) *ContextGroup {
+	return cm.ContextGroup.NewGroup(path)
+}
+
+// GET is convenience method for handling GET requests on a context group.
+func (cm *ContextMux) GET(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("GET", path, handler)
+}
+
+// POST is convenience method for handling POST requests on a context group.
+func (cm *ContextMux) POST(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("POST", path, handler)
+}
+
+// PUT is convenience method for handling PUT requests on a context group.
+func (cm *ContextMux) PUT(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("PUT", path, handler)
+}
+
+// DELETE is convenience method for handling DELETE requests on a context group.
+func (cm *ContextMux) DELETE(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("DELETE", path, handler)
+}
+
+// PATCH is convenience method for handling PATCH requests on a context group.
+func (cm *ContextMux) PATCH(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("PATCH", path, handler)
+}
+
+// HEAD is convenience method for handling HEAD requests on a context group.
+func (cm *ContextMux) HEAD(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("HEAD", path, handler)
+}
+
+// OPTIONS is convenience method for handling OPTIONS requests on a context group.
+func (cm *ContextMux) OPTIONS(path string, handler HandlerFunc) *Route {
+	return cm.ContextGroup.Handle("OPTIONS", path, handler)
+}
@@ -0,0 +1,31 @@
+package lambdarouter
+
+import "testing"
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/abc/def", "/abc/def"},
+		{"/abc%2Fdef", "/abc/def"},
+		{"/abc%2fdef", "/abc/def"},
+		// Unlike in a query, + is not a space in a path.
+		{"/a+b", "/a+b"},
+		{"/a%2Bb", "/a+b"},
+		{"/a%20b", "/a b"},
+	}
+	for _, test := range tests {
+		got, err := unescape(test.path)
+		if err != nil {
+			t.Errorf("Unescaping %s failed: %s", test.path, err)
+		}
+		if got != test.expected {
+			t.Errorf("Unescaping %s expected %s, saw %s", test.path, test.expected, got)
+		}
+	}
+
+	if _, err := unescape("/abc%zz"); err == nil {
+		t.Error("Expected an error for an invalid escape")
+	}
+}
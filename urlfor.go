@@ -0,0 +1,49 @@
+package lambdarouter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLFor returns the path of the route named name, with its wildcards and catch-all
+// replaced by the escaped values of params:
+//
+//	router.GET("/hello/:name", hello).Name("hello")
+//	router.URLFor("hello", map[string]string{"name": "Jane Doe"}) // /hello/Jane%20Doe
+//
+// The slashes of a catch-all value are kept. An error is returned for unknown names
+// and for missing parameters. When running locally the path is given without its stage.
+func (t *TreeMux) URLFor(name string, params map[string]string) (string, error) {
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+
+	route, ok := t.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("lambdarouter: no route named %q", name)
+	}
+
+	var segments []string
+	for _, segment := range splitPattern(route.path) {
+		if !segment.wildcard && !segment.catchAll {
+			segments = append(segments, segment.text)
+			continue
+		}
+		value, ok := params[segment.text]
+		if !ok {
+			return "", fmt.Errorf("lambdarouter: missing parameter %q for route %q", segment.text, name)
+		}
+		if segment.wildcard {
+			segments = append(segments, url.PathEscape(value))
+			continue
+		}
+		parts := strings.Split(value, "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		segments = append(segments, strings.Join(parts, "/"))
+	}
+	return strings.Join(segments, "/"), nil
+}
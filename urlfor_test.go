@@ -0,0 +1,42 @@
+package lambdarouter
+
+import "testing"
+
+func TestURLFor(t *testing.T) {
+	router := New()
+	router.GET("/hello/:name", simpleHandler).Name("hello")
+	router.GET("/files/:bucket/*path", simpleHandler).Name("file")
+	router.GET("/date/\\:year", simpleHandler).Name("literal")
+
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{
+		{"hello", map[string]string{"name": "bob"}, "/hello/bob"},
+		{"hello", map[string]string{"name": "Jane Doe/?"}, "/hello/Jane%20Doe%2F%3F"},
+		{"file", map[string]string{"bucket": "docs", "path": "a b/c.txt"}, "/files/docs/a%20b/c.txt"},
+		{"file", map[string]string{"bucket": "docs", "path": ""}, "/files/docs/"},
+		{"literal", nil, "/date/:year"},
+	}
+	for _, test := range tests {
+		url, err := router.URLFor(test.name, test.params)
+		if err != nil || url != test.expected {
+			t.Errorf("%s with %v expected %s, saw %s (%v)", test.name, test.params, test.expected, url, err)
+		}
+	}
+
+	if _, err := router.URLFor("file", map[string]string{"bucket": "docs"}); err == nil {
+		t.Error("Expected an error for a missing parameter")
+	}
+	if _, err := router.URLFor("unknown", nil); err == nil {
+		t.Error("Expected an error for an unknown route")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a duplicate route name to panic")
+		}
+	}()
+	router.GET("/other", simpleHandler).Name("hello")
+}
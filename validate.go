@@ -0,0 +1,75 @@
+package lambdarouter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Validate looks for registered patterns which overlap, that is a more specific
+// pattern taking every request a more general one would otherwise get, such as
+// /files/special and /files/*path. This is often intended, but also a common source
+// of routing bugs, so it returns a warning describing each overlap to check before
+// deploying. The warnings are sorted.
+func (t *TreeMux) Validate() []string {
+	if t.SafeAddRoutesWhileRunning {
+		t.mutex.RLock()
+		defer t.mutex.RUnlock()
+	}
+
+	var patterns []string
+	t.root.walk(func(n *node) {
+		patterns = append(patterns, n.pattern)
+	})
+
+	var warnings []string
+	for _, specific := range patterns {
+		for _, general := range patterns {
+			if specific != general && patternCovers(general, specific) {
+				warnings = append(warnings, fmt.Sprintf("%s shadows %s for the requests it matches", specific, general))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// patternCovers reports whether general matches every path specific matches.
+func patternCovers(general, specific string) bool {
+	g := strings.Split(strings.Trim(general, "/"), "/")
+	s := strings.Split(strings.Trim(specific, "/"), "/")
+	for i, segment := range g {
+		if strings.HasPrefix(segment, "*") {
+			// A catch-all takes the rest of the path, which must not be empty.
+			return i < len(s) && s[i] != ""
+		}
+		if i >= len(s) || strings.HasPrefix(s[i], "*") {
+			return false
+		}
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			if s[i] == "" || !wildcardCovers(segment, s[i]) {
+				return false
+			}
+		case segment != s[i] || strings.HasPrefix(s[i], ":"):
+			return false
+		}
+	}
+	return len(g) == len(s)
+}
+
+// wildcardCovers reports whether the wildcard segment matches every value of the
+// segment specific. A wildcard constrained by a regular expression only covers the
+// static segments it matches and the wildcards with the same expression.
+func wildcardCovers(wildcard, specific string) bool {
+	_, expr := splitWildcard(wildcard[1:])
+	if expr == "" {
+		return true
+	}
+	if strings.HasPrefix(specific, ":") {
+		_, specificExpr := splitWildcard(specific[1:])
+		return specificExpr == expr
+	}
+	return regexp.MustCompile("^(?:" + expr + ")$").MatchString(specific)
+}
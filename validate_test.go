@@ -0,0 +1,52 @@
+package lambdarouter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	router := New()
+	router.GET("/files/*path", simpleHandler)
+	router.GET("/files/special", simpleHandler)
+	router.GET("/users/:id", simpleHandler)
+	router.POST("/users/me", simpleHandler)
+	router.GET("/users/:id/posts", simpleHandler)
+	router.GET("/", simpleHandler)
+
+	expected := []string{
+		"/files/special shadows /files/*path for the requests it matches",
+		"/users/me shadows /users/:id for the requests it matches",
+	}
+	if warnings := router.Validate(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected warnings %q, saw %q", expected, warnings)
+	}
+
+	if warnings := newLambdaRouter().Validate(); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for an empty router, saw %q", warnings)
+	}
+}
+
+func TestPatternCovers(t *testing.T) {
+	tests := []struct {
+		general, specific string
+		expected          bool
+	}{
+		{"/files/*path", "/files/special", true},
+		{"/files/*path", "/files/a/b", true},
+		{"/files/*path", "/files/:name", true},
+		{"/files/*path", "/files", false},
+		{"/:page", "/abc", true},
+		{"/:page", "/:other", true},
+		{"/:page", "/abc/def", false},
+		{"/abc", "/:page", false},
+		{"/:page", "/*path", false},
+		{"/a/:b/c", "/a/x/c", true},
+		{"/a/:b/c", "/a/x/d", false},
+	}
+	for _, test := range tests {
+		if got := patternCovers(test.general, test.specific); got != test.expected {
+			t.Errorf("%s covering %s expected %v, saw %v", test.general, test.specific, test.expected, got)
+		}
+	}
+}
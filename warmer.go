@@ -0,0 +1,42 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// IsWarmerEvent reports whether raw is the {"warmer": true} payload sent by
+// scheduled events to keep the function warm. New uses it as the warmer detector.
+func IsWarmerEvent(raw json.RawMessage) bool {
+	var probe struct {
+		Warmer bool `json:"warmer"`
+	}
+	return json.Unmarshal(raw, &probe) == nil && probe.Warmer
+}
+
+// SetWarmerDetector sets the function recognizing the warming pings received by
+// LambdaHandler. They are answered with an empty success, without being routed,
+// after calling the function given to SetWarmup. Passing nil treats every payload as
+// an event to serve.
+func (t *TreeMux) SetWarmerDetector(detect func(raw json.RawMessage) bool) {
+	t.warmerDetector = detect
+}
+
+// SetWarmup sets a function called for each warming ping, to open the connections
+// the handlers need ahead of the first request. IsColdStart tells whether the ping
+// started the process. An error is returned as the result of the invocation.
+func (t *TreeMux) SetWarmup(warmup func(ctx context.Context) error) {
+	t.warmup = warmup
+}
+
+// serveWarmer answers a warming ping. It counts as the cold start of the process,
+// so that the request following it is not reported as one.
+func (t *TreeMux) serveWarmer(ctx context.Context) (interface{}, error) {
+	ctx, _ = markColdStart(t.withDefaultContext(ctx))
+	if t.warmup != nil {
+		if err := t.warmup(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
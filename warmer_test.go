@@ -0,0 +1,63 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestWarmer(t *testing.T) {
+	defer atomic.StoreInt32(&warm, atomic.LoadInt32(&warm))
+	atomic.StoreInt32(&warm, 0)
+
+	var served int
+	router := newLambdaRouter()
+	router.GET("/*path", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		served++
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+	router.NotFoundHandler = func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		served++
+		return LambdaNotFound(ctx, req)
+	}
+	var warmups []bool
+	router.SetWarmup(func(ctx context.Context) error {
+		warmups = append(warmups, IsColdStart(ctx))
+		return nil
+	})
+	handler := router.LambdaHandler()
+
+	if res, err := handler(context.Background(), json.RawMessage(`{"warmer": true}`)); res != nil || err != nil {
+		t.Errorf("Expected an empty success for a warming ping, saw %v %v", res, err)
+	}
+	if len(warmups) != 1 || !warmups[0] {
+		t.Errorf("Expected the first ping to warm up a cold start, saw %v", warmups)
+	}
+
+	// An HTTP shaped ping is not routed either.
+	ping := json.RawMessage(`{"httpMethod": "GET", "resource": "/ping", "path": "/ping", "source": "warmer"}`)
+	router.SetWarmerDetector(func(raw json.RawMessage) bool {
+		var probe struct{ Source string }
+		return json.Unmarshal(raw, &probe) == nil && probe.Source == "warmer"
+	})
+	if _, err := handler(context.Background(), ping); err != nil || served != 0 {
+		t.Errorf("Expected the ping not to reach the routes, saw %d calls and %v", served, err)
+	}
+	if len(warmups) != 2 || warmups[1] {
+		t.Errorf("Expected a second warm up without a cold start, saw %v", warmups)
+	}
+
+	router.SetWarmup(func(ctx context.Context) error { return errors.New("no database") })
+	if _, err := handler(context.Background(), ping); err == nil || err.Error() != "no database" {
+		t.Errorf("Expected the error of the warm up, saw %v", err)
+	}
+
+	router.SetWarmerDetector(nil)
+	if res, _ := handler(context.Background(), ping); res.(events.APIGatewayProxyResponse).StatusCode != 200 || served != 1 {
+		t.Errorf("Expected the payload to be routed without a detector, saw %v", res)
+	}
+}
@@ -0,0 +1,142 @@
+package lambdarouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WebsocketHandlerFunc handles a message received on an API Gateway WebSocket API.
+type WebsocketHandlerFunc func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// WebsocketMux dispatches WebSocket events to the handler registered for their
+// route key. Set it as TreeMux.Websocket to serve it with LambdaHandler.
+type WebsocketMux struct {
+	wsevent map[string]WebsocketHandlerFunc
+
+	// templateSelectionExpression picks the route of messages API Gateway sent to
+	// $default, for APIs routing every message there.
+	templateSelectionExpression string
+}
+
+// NewWebsocket returns an empty WebsocketMux.
+func NewWebsocket() *WebsocketMux {
+	return &WebsocketMux{
+		wsevent: make(map[string]WebsocketHandlerFunc),
+	}
+}
+
+// On registers handler for a route key, either one of the predefined `$connect`,
+// `$disconnect` and `$default` routes or a custom one.
+func (ws *WebsocketMux) On(route string, handler WebsocketHandlerFunc) {
+	ws.wsevent[route] = handler
+}
+
+// SetRouteSelectionExpression sets the expression routing the messages API Gateway
+// sent to $default, or to a route key without handler, for APIs routing every
+// message there, such as $request.body.action. See
+// ResolveTemplateSelectionExpression for the supported expressions.
+func (ws *WebsocketMux) SetRouteSelectionExpression(expr string) {
+	ws.templateSelectionExpression = expr
+}
+
+// ServeWebsocket serves a WebSocket event with TreeMux.Websocket, which answers 404
+// Not Found when it is not set. LambdaHandler calls it for WebSocket events.
+func (t *TreeMux) ServeWebsocket(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if t.Websocket == nil {
+		return LambdaNotFound(ctx, events.APIGatewayProxyRequest{})
+	}
+	return t.Websocket.dispatch(ctx, req)
+}
+
+// dispatch calls the handler of the route key of req. Messages sent to $default or
+// to a route key without a handler are routed with the template selection
+// expression, if any, and then fall back to $default.
+func (ws *WebsocketMux) dispatch(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	routeKey := req.RequestContext.RouteKey
+	handler, ok := ws.wsevent[routeKey]
+	if (!ok || routeKey == "$default") && ws.templateSelectionExpression != "" {
+		if selected, found := ws.wsevent[ResolveTemplateSelectionExpression(ws.templateSelectionExpression, req)]; found {
+			handler, ok = selected, true
+		}
+	}
+	if !ok {
+		handler, ok = ws.wsevent["$default"]
+	}
+	if !ok {
+		return LambdaNotFound(ctx, events.APIGatewayProxyRequest{})
+	}
+	return handler(ctx, req)
+}
+
+// ResolveTemplateSelectionExpression evaluates a selection expression against the
+// JSON body of a WebSocket message, as API Gateway does to pick its route. The
+// expression is either a single reference such as $request.body.action, or a
+// template such as ${request.body.service}/${request.body.action}. References to
+// missing fields resolve to an empty string.
+func ResolveTemplateSelectionExpression(expr string, req events.APIGatewayWebsocketProxyRequest) string {
+	var body interface{}
+	json.Unmarshal([]byte(req.Body), &body)
+
+	if strings.HasPrefix(expr, "$request.") {
+		return resolveSelection(expr[1:], body)
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(expr, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(expr[start:], '}')
+		if end < 0 {
+			break
+		}
+		out.WriteString(expr[:start])
+		out.WriteString(resolveSelection(expr[start+2:start+end], body))
+		expr = expr[start+end+1:]
+	}
+	out.WriteString(expr)
+	return out.String()
+}
+
+// resolveSelection returns the value of a request.body.a.b reference in body.
+func resolveSelection(ref string, body interface{}) string {
+	if !strings.HasPrefix(ref, "request.body.") {
+		return ""
+	}
+	value := body
+	for _, key := range strings.Split(ref[len("request.body."):], ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = object[key]
+	}
+
+	switch v := value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ConnectionID returns the ID of the connection which sent req, used to send it
+// messages through the API Gateway management API.
+func ConnectionID(req events.APIGatewayWebsocketProxyRequest) string {
+	return req.RequestContext.ConnectionID
+}
+
+// ManagementEndpoint returns the URL of the API Gateway management API for the
+// connection which sent req, such as https://abc123.execute-api.eu-west-1.amazonaws.com/prod.
+// It is built from the domain and stage of the request, so with a custom domain the
+// stage is the one of the API mapping.
+func ManagementEndpoint(req events.APIGatewayWebsocketProxyRequest) string {
+	return "https://" + req.RequestContext.DomainName + "/" + req.RequestContext.Stage
+}
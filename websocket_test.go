@@ -0,0 +1,131 @@
+package lambdarouter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func newWebsocketRequest(routeKey, body string) events.APIGatewayWebsocketProxyRequest {
+	req := events.APIGatewayWebsocketProxyRequest{Body: body}
+	req.RequestContext.ConnectionID = "abc="
+	req.RequestContext.RouteKey = routeKey
+	return req
+}
+
+func TestWebsocketDispatch(t *testing.T) {
+	var called string
+	ws := NewWebsocket()
+	for _, route := range []string{"$connect", "$disconnect", "$default", "sendMessage", "chat/join"} {
+		route := route
+		ws.On(route, func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+			called = route
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		})
+	}
+
+	tests := []struct {
+		routeKey string
+		body     string
+		expected string
+	}{
+		{"$connect", "", "$connect"},
+		{"$disconnect", "", "$disconnect"},
+		{"sendMessage", `{"action": "sendMessage"}`, "sendMessage"},
+		{"$default", `{"action": "unknown"}`, "$default"},
+		{"unregistered", "", "$default"},
+	}
+	for _, test := range tests {
+		called = ""
+		res, err := ws.dispatch(context.Background(), newWebsocketRequest(test.routeKey, test.body))
+		if err != nil || res.StatusCode != http.StatusOK || called != test.expected {
+			t.Errorf("Route key %s expected the %s handler, saw %q with %d", test.routeKey, test.expected, called, res.StatusCode)
+		}
+	}
+
+	// Messages sent to $default are routed with the selection expression.
+	ws.SetRouteSelectionExpression("${request.body.service}/${request.body.action}")
+	called = ""
+	ws.dispatch(context.Background(), newWebsocketRequest("$default", `{"service": "chat", "action": "join"}`))
+	if called != "chat/join" {
+		t.Errorf("Expected the chat/join handler, saw %q", called)
+	}
+
+	empty := NewWebsocket()
+	res, _ := empty.dispatch(context.Background(), newWebsocketRequest("$connect", ""))
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without a handler, saw %d", res.StatusCode)
+	}
+}
+
+func TestServeWebsocket(t *testing.T) {
+	router := newLambdaRouter()
+	res, _ := router.ServeWebsocket(context.Background(), newWebsocketRequest("$connect", ""))
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without TreeMux.Websocket, saw %d", res.StatusCode)
+	}
+
+	var called []string
+	router.Websocket = NewWebsocket()
+	router.Websocket.SetRouteSelectionExpression("$request.body.action")
+	for _, route := range []string{"$connect", "sendMessage"} {
+		route := route
+		router.Websocket.On(route, func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+			called = append(called, route+" "+ConnectionID(req))
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		})
+	}
+
+	if res, err := router.ServeWebsocket(context.Background(), newWebsocketRequest("$connect", "")); err != nil || res.StatusCode != http.StatusOK {
+		t.Errorf("Expected $connect to be served, saw %d, %v", res.StatusCode, err)
+	}
+	raw := mustMarshal(t, newWebsocketRequest("$default", `{"action": "sendMessage", "text": "hi"}`))
+	if _, err := router.LambdaHandler()(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"$connect abc=", "sendMessage abc="}; !reflect.DeepEqual(called, expected) {
+		t.Errorf("Expected %v, saw %v", expected, called)
+	}
+}
+
+func TestResolveTemplateSelectionExpression(t *testing.T) {
+	req := newWebsocketRequest("$default", `{"action": "send", "meta": {"version": 2, "beta": true}}`)
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"$request.body.action", "send"},
+		{"$request.body.meta.version", "2"},
+		{"$request.body.meta", ""},
+		{"$request.body.missing", ""},
+		{"${request.body.action}-v${request.body.meta.version}", "send-v2"},
+		{"beta:${request.body.meta.beta}", "beta:true"},
+		{"static", "static"},
+	}
+	for _, test := range tests {
+		if resolved := ResolveTemplateSelectionExpression(test.expr, req); resolved != test.expected {
+			t.Errorf("%s expected %q, saw %q", test.expr, test.expected, resolved)
+		}
+	}
+
+	if resolved := ResolveTemplateSelectionExpression("$request.body.action", newWebsocketRequest("$default", "not json")); resolved != "" {
+		t.Errorf("Expected an empty route for a body which is not JSON, saw %q", resolved)
+	}
+}
+
+func TestWebsocketRequestHelpers(t *testing.T) {
+	req := newWebsocketRequest("$connect", "")
+	req.RequestContext.DomainName = "abc123.execute-api.eu-west-1.amazonaws.com"
+	req.RequestContext.Stage = "prod"
+
+	if id := ConnectionID(req); id != "abc=" {
+		t.Errorf("Expected the connection ID abc=, saw %q", id)
+	}
+	if endpoint := ManagementEndpoint(req); endpoint != "https://abc123.execute-api.eu-west-1.amazonaws.com/prod" {
+		t.Errorf("Expected the management endpoint of the prod stage, saw %q", endpoint)
+	}
+}